@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mstergianis/huffman/pkg/huffman"
+)
+
+func pack(args []string) error {
+	var (
+		outputFile string
+		force      bool
+	)
+	flags := newFlagSet("pack", "[-o OUTPUT-FILE] [-f] PATH...",
+		"Packs every file and directory named by PATH, descending into directories, into\n"+
+			"an archive. Entries are stored relative to the directory containing PATH.")
+	flags.stringVar(&outputFile, "o", "output", "", "FILE", "write to FILE, - or no file means stdout")
+	flags.boolVar(&force, "f", "force", "overwrite OUTPUT-FILE, and write compressed data even if stdout is a terminal")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return usageErrorf("pack", "expected at least one file or directory")
+	}
+
+	f, err := createOutput(outputFile, force)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if isTerminal(f.File) && !force {
+		return fmt.Errorf("error: refusing to write compressed data to a terminal, use -f to force it")
+	}
+
+	// the archive must not pack itself, neither its temporary file nor the
+	// file it replaces
+	skip := func(string, fs.DirEntry) bool { return false }
+	if f.temp {
+		temp, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		output, err := filepath.Abs(f.path)
+		if err != nil {
+			return err
+		}
+		skip = func(p string, d fs.DirEntry) bool {
+			if abs, err := filepath.Abs(p); err == nil && abs == output {
+				return true
+			}
+			info, err := d.Info()
+			return err == nil && os.SameFile(info, temp)
+		}
+	}
+
+	w := bufio.NewWriter(f)
+	aw := huffman.NewArchiveWriter(w)
+	for _, root := range flags.Args() {
+		// store entries relative to the directory holding root, as tar does
+		prefix := filepath.Base(filepath.Clean(root))
+		if prefix == "." || prefix == ".." || prefix == string(filepath.Separator) {
+			prefix = ""
+		}
+
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			name := path.Join(prefix, filepath.ToSlash(rel))
+			if name == "." || skip(p, d) {
+				return nil
+			}
+			return packEntry(aw, p, name, d)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := aw.Close(); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.commit()
+}
+
+func packEntry(aw *huffman.ArchiveWriter, p, name string, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	e := huffman.ArchiveEntry{
+		Path:    name,
+		Mode:    info.Mode(),
+		ModTime: info.ModTime(),
+		Size:    info.Size(),
+	}
+
+	switch {
+	case info.IsDir():
+		return aw.Add(e, nil)
+	case info.Mode().IsRegular():
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return aw.Add(e, f)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: skipping %s, it is neither a regular file nor a directory\n", programName, p)
+	return nil
+}
+
+func list(args []string) error {
+	flags := newFlagSet("list", "ARCHIVE",
+		"Lists the entries of ARCHIVE, - means stdin.")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageErrorf("list", "expected exactly one archive")
+	}
+
+	ar, closer, err := openArchive(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, e := range ar.Entries {
+		name := e.Path
+		if e.Mode.IsDir() {
+			name += "/"
+		}
+		fmt.Fprintf(w, "%s %12d %s %s\n", e.Mode, e.Size, e.ModTime.Format(time.DateTime), name)
+	}
+	return w.Flush()
+}
+
+func unpack(args []string) error {
+	var (
+		dest  string
+		force bool
+	)
+	flags := newFlagSet("unpack", "ARCHIVE [-C DIRECTORY] [-f]",
+		"Unpacks every entry of ARCHIVE, - means stdin, into DIRECTORY. Entries that would\n"+
+			"land outside of DIRECTORY are refused, and so are files that already exist\n"+
+			"unless -f is given.")
+	flags.stringVar(&dest, "C", "directory", ".", "DIRECTORY", "unpack into DIRECTORY (default .)")
+	flags.boolVar(&force, "f", "force", "overwrite files that already exist in DIRECTORY")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageErrorf("unpack", "expected exactly one archive")
+	}
+
+	ar, closer, err := openArchive(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	// an os.Root refuses to follow anything, symlinks included, out of dest
+	root, err := os.OpenRoot(dest)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	var dirs []huffman.ArchiveEntry
+	for _, e := range ar.Entries {
+		name := filepath.FromSlash(e.Path)
+		if !filepath.IsLocal(name) {
+			return &huffman.CorruptInputError{Offset: -1, Err: fmt.Errorf("error: refusing to unpack %q outside of %s", e.Path, dest)}
+		}
+		if err := mkdirAll(root, filepath.Dir(name)); err != nil {
+			return err
+		}
+
+		// directories get their own mode once their contents are in, since
+		// it may not let them be written
+		if e.Mode.IsDir() {
+			if err := root.Mkdir(name, 0700); err != nil && !errors.Is(err, fs.ErrExist) {
+				return err
+			}
+			dirs = append(dirs, e)
+			continue
+		}
+
+		if err := unpackFile(root, ar, e, name, force); err != nil {
+			return err
+		}
+		if err := root.Chtimes(name, e.ModTime, e.ModTime); err != nil {
+			return err
+		}
+	}
+
+	// unpacking files into a directory changes its modification time, so
+	// directories are dated last, and the deepest go first so that a parent's
+	// mode doesn't stand in the way of its children
+	slices.SortStableFunc(dirs, func(a, b huffman.ArchiveEntry) int {
+		return cmp.Compare(strings.Count(b.Path, "/"), strings.Count(a.Path, "/"))
+	})
+	for _, e := range dirs {
+		name := filepath.FromSlash(e.Path)
+		if err := root.Chmod(name, e.Mode.Perm()); err != nil {
+			return err
+		}
+		if err := root.Chtimes(name, e.ModTime, e.ModTime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unpackFile writes the file e to name within root, refusing to overwrite an
+// existing file unless force is set.
+func unpackFile(root *os.Root, ar *huffman.ArchiveReader, e huffman.ArchiveEntry, name string, force bool) error {
+	flag := os.O_CREATE | os.O_WRONLY | os.O_EXCL
+	if force {
+		flag = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+	f, err := root.OpenFile(name, flag, 0600)
+	if errors.Is(err, fs.ErrExist) {
+		return &fs.PathError{Op: "create", Path: filepath.Join(root.Name(), name), Err: errors.New("file already exists, use -f to overwrite it")}
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, ar.Open(e)); err != nil {
+		return fmt.Errorf("error: while unpacking %q: %w", e.Path, err)
+	}
+	// an overwritten file keeps its mode otherwise
+	if err := f.Chmod(e.Mode.Perm()); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// mkdirAll creates dir and its parents within root.
+func mkdirAll(root *os.Root, dir string) error {
+	if dir == "." {
+		return nil
+	}
+	current := ""
+	for _, part := range strings.Split(dir, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		if err := root.Mkdir(current, 0755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// openArchive opens the archive at path, reading stdin into memory when path
+// is "-".
+func openArchive(path string) (*huffman.ArchiveReader, io.Closer, error) {
+	var (
+		r      io.ReaderAt
+		size   int64
+		closer io.Closer = io.NopCloser(nil)
+	)
+	if path == "-" {
+		contents, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, nil, err
+		}
+		r, size = bytes.NewReader(contents), int64(len(contents))
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		r, size, closer = f, info.Size(), f
+	}
+
+	ar, err := huffman.OpenArchive(r, size)
+	if err != nil {
+		closer.Close()
+		return nil, nil, err
+	}
+	return ar, closer, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mstergianis/huffman/pkg/huffman"
+)
+
+type benchResult struct {
+	File           string  `json:"file"`
+	Size           int     `json:"size"`
+	EncodedSize    int     `json:"encodedSize"`
+	Ratio          float64 `json:"ratio"`
+	EncodeMBPerSec float64 `json:"encodeMBPerSec"`
+	DecodeMBPerSec float64 `json:"decodeMBPerSec"`
+	EncodeAllocs   uint64  `json:"encodeAllocsPerOp"`
+	EncodeBytes    uint64  `json:"encodeBytesPerOp"`
+	DecodeAllocs   uint64  `json:"decodeAllocsPerOp"`
+	DecodeBytes    uint64  `json:"decodeBytesPerOp"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// bench runs Encode and Decode over every file named in args, descending into
+// directories, and reports how well and how quickly they compress.
+func bench(args []string) error {
+	var (
+		iterations int
+		asJSON     bool
+	)
+	flags := newFlagSet("bench", "[-n ITERATIONS] [--json] FILE-OR-DIRECTORY...",
+		"Encodes and decodes every file, descending into directories, and reports the\n"+
+			"compression ratio, throughput and allocations of each.")
+	flags.intVar(&iterations, "n", "iterations", 10, "N", "encode and decode every file N times")
+	flags.boolVar(&asJSON, "", "json", "report as JSON rather than a table")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if iterations < 1 {
+		return usageErrorf("bench", "--iterations must be at least 1, got %d", iterations)
+	}
+	if flags.NArg() == 0 {
+		return usageErrorf("bench", "expected at least one file or directory")
+	}
+
+	var files []string
+	for _, p := range flags.Args() {
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	results := make([]benchResult, 0, len(files))
+	for _, file := range files {
+		input, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		results = append(results, benchFile(file, input, iterations))
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tSIZE\tENCODED\tRATIO\tENCODE MB/s\tDECODE MB/s\tENCODE ALLOCS\tDECODE ALLOCS\t")
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\t%d\t%s\t\t\t\t\t\t\n", r.File, r.Size, r.Error)
+			continue
+		}
+		fmt.Fprintf(
+			w, "%s\t%d\t%d\t%.3f\t%.2f\t%.2f\t%d\t%d\t\n",
+			r.File, r.Size, r.EncodedSize, r.Ratio,
+			r.EncodeMBPerSec, r.DecodeMBPerSec, r.EncodeAllocs, r.DecodeAllocs,
+		)
+	}
+	return w.Flush()
+}
+
+func benchFile(file string, input []byte, iterations int) benchResult {
+	result := benchResult{File: file, Size: len(input)}
+
+	var encoded []byte
+	encodeTime, encodeAllocs, encodeBytes, err := measure(iterations, func() (err error) {
+		encoded, err = huffman.Encode(input)
+		return err
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var decoded []byte
+	decodeTime, decodeAllocs, decodeBytes, err := measure(iterations, func() (err error) {
+		decoded, err = huffman.Decode(encoded)
+		return err
+	})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if !bytes.Equal(input, decoded) {
+		result.Error = "error: decoded output does not match the input"
+		return result
+	}
+
+	result.EncodedSize = len(encoded)
+	if len(input) > 0 {
+		result.Ratio = float64(len(encoded)) / float64(len(input))
+	}
+	result.EncodeMBPerSec = megabytesPerSecond(len(input)*iterations, encodeTime)
+	result.DecodeMBPerSec = megabytesPerSecond(len(input)*iterations, decodeTime)
+	result.EncodeAllocs = encodeAllocs
+	result.EncodeBytes = encodeBytes
+	result.DecodeAllocs = decodeAllocs
+	result.DecodeBytes = decodeBytes
+	return result
+}
+
+// measure runs f iterations times, returning the total time taken and the
+// number of allocations and allocated bytes per run.
+func measure(iterations int, f func() error) (elapsed time.Duration, allocs, bytes uint64, err error) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for range iterations {
+		if err = f(); err != nil {
+			return
+		}
+	}
+	elapsed = time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	allocs = (after.Mallocs - before.Mallocs) / uint64(iterations)
+	bytes = (after.TotalAlloc - before.TotalAlloc) / uint64(iterations)
+	return
+}
+
+func megabytesPerSecond(n int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / 1e6 / d.Seconds()
+}
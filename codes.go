@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mstergianis/huffman/pkg/huffman"
+)
+
+func codes(args []string) error {
+	var (
+		inputFile string
+		fromText  bool
+	)
+	flags := newFlagSet("codes", "[-i INPUT-FILE] [--from-text]",
+		"Prints the code of every symbol in the tree of the encoded INPUT-FILE. With\n"+
+			"--from-text INPUT-FILE is read as plain input, and the codes that encoding it\n"+
+			"would use are printed instead.")
+	flags.stringVar(&inputFile, "i", "input", "", "FILE", "read from FILE, - or no file means stdin")
+	flags.boolVar(&fromText, "t", "from-text", "build the tree from plain input")
+	if err := parseNoArgs(flags, args); err != nil {
+		return err
+	}
+
+	input, err := openInput(inputFile)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	contents, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+
+	var tree *huffman.Node
+	if fromText {
+		tree = huffman.NewNodeFromInput(contents)
+	} else {
+		tree, err = readTree(contents)
+		if err != nil {
+			return err
+		}
+	}
+
+	table, err := huffman.NewCodeTable(tree)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "SYMBOL\tLENGTH\tCODE\n")
+	for symbol, code := range table.All() {
+		fmt.Fprintf(w, "%q\t%d\t%s\n", string([]byte{symbol}), code.Length, code)
+	}
+	if code, ok := table.EndOfBlock(); ok {
+		fmt.Fprintf(w, "EOB\t%d\t%s\n", code.Length, code)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mstergianis/huffman/pkg/huffman"
+)
+
+func encode(args []string) error {
+	var (
+		inputFile  string
+		outputFile string
+		force      bool
+		verify     bool
+		endOfBlock bool
+		progress   bool
+	)
+	flags := newFlagSet("encode", "[-i INPUT-FILE] [-o OUTPUT-FILE] [-f] [--verify] [--end-of-block] [--progress]",
+		"Encodes INPUT-FILE into OUTPUT-FILE. Encoding stdin writes a block stream, which\n"+
+			"decode detects on its own.\n\n"+
+			"--end-of-block only changes how encoded content ends, not how it is read or\n"+
+			"stored: files are still read whole, and a block stream still stores the length\n"+
+			"of every block ahead of it.")
+	flags.stringVar(&inputFile, "i", "input", "", "FILE", "read from FILE, - or no file means stdin")
+	flags.stringVar(&outputFile, "o", "output", "", "FILE", "write to FILE, - or no file means stdout")
+	flags.boolVar(&force, "f", "force", "overwrite OUTPUT-FILE, and write compressed data even if stdout is a terminal")
+	flags.boolVar(&verify, "", "verify", "decode the output again and check that it matches the input")
+	flags.boolVar(&endOfBlock, "", "end-of-block", "end the content with an end of block symbol instead of storing its length")
+	flags.boolVar(&progress, "", "progress", "draw a progress bar on stderr, if it is a terminal")
+	if err := parseNoArgs(flags, args); err != nil {
+		return err
+	}
+
+	input, err := openInput(inputFile)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	f, err := createOutput(outputFile, force)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if isTerminal(f.File) && !force {
+		return fmt.Errorf("error: refusing to write compressed data to a terminal, use -f to force it")
+	}
+
+	bar := newProgressBar(progress, input)
+	defer bar.close()
+	w := bufio.NewWriter(f)
+	if err := encodeTo(w, input, verify, endOfBlock, bar.callback()); err != nil {
+		return err
+	}
+	bar.finish()
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.commit()
+}
+
+// encodeTo encodes input into w. Files are encoded whole, stdin is streamed as a
+// block stream since its length isn't known up front. With verify the encoded
+// data is decoded again and checked against input, with endOfBlock content is
+// ended by the end of block symbol rather than its length. That only changes the
+// terminator within each encoding: blocks are still written with their lengths,
+// and files are still encoded whole.
+func encodeTo(w io.Writer, input *os.File, verify, endOfBlock bool, progress huffman.ProgressFunc) error {
+	if input == os.Stdin {
+		bw := huffman.NewBlockWriter(w, huffman.DefaultBlockSize)
+		if verify {
+			bw.VerifyBlocks()
+		}
+		if endOfBlock {
+			bw.EndOfBlock()
+		}
+		bw.OnProgress(progress)
+		if _, err := io.Copy(bw, input); err != nil {
+			return err
+		}
+		return bw.Close()
+	}
+
+	contents, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+	encode := huffman.EncodeTo
+	if endOfBlock {
+		encode = huffman.EncodeEndOfBlockTo
+	}
+	if !verify {
+		return encode(w, contents, progress)
+	}
+
+	encoded := &bytes.Buffer{}
+	if err := encode(encoded, contents, progress); err != nil {
+		return err
+	}
+	decoded, err := huffman.Decode(encoded.Bytes())
+	if err != nil {
+		return fmt.Errorf("%w: %w", huffman.ErrVerifyMismatch, err)
+	}
+	if !bytes.Equal(decoded, contents) {
+		return huffman.ErrVerifyMismatch
+	}
+	_, err = w.Write(encoded.Bytes())
+	return err
+}
+
+func decode(args []string) error {
+	var (
+		inputFile  string
+		outputFile string
+		force      bool
+		progress   bool
+	)
+	flags := newFlagSet("decode", "[-i INPUT-FILE] [-o OUTPUT-FILE] [-f] [--progress]",
+		"Decodes INPUT-FILE, which may be an encoded file or a block stream, into\n"+
+			"OUTPUT-FILE. Block streams are decoded as they are read and their index and\n"+
+			"checksums are only checked after the last block, so on stdout the blocks\n"+
+			"before a failure have already been written. OUTPUT-FILE is only put in place\n"+
+			"once the whole input has decoded and passed those checks.")
+	flags.stringVar(&inputFile, "i", "input", "", "FILE", "read from FILE, - or no file means stdin")
+	flags.stringVar(&outputFile, "o", "output", "", "FILE", "write to FILE, - or no file means stdout")
+	flags.boolVar(&force, "f", "force", "overwrite OUTPUT-FILE if it exists")
+	flags.boolVar(&progress, "", "progress", "draw a progress bar on stderr, if it is a terminal")
+	if err := parseNoArgs(flags, args); err != nil {
+		return err
+	}
+
+	input, err := openInput(inputFile)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	f, err := createOutput(outputFile, force)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bar := newProgressBar(progress, input)
+	defer bar.close()
+	if err := decodeTo(f, input, bar.callback()); err != nil {
+		return err
+	}
+	bar.finish()
+	return f.commit()
+}
+
+// decodeTo decodes input into w, telling encoded files and block streams apart
+// by their headers.
+func decodeTo(w io.Writer, input io.Reader, progress huffman.ProgressFunc) error {
+	r := bufio.NewReader(input)
+	header, _ := r.Peek(4)
+	if huffman.IsArchive(header) {
+		return fmt.Errorf("error: the input is an archive, use unpack to extract it")
+	}
+	if huffman.IsBlockStream(header) {
+		br := huffman.NewBlockReader(r)
+		br.OnProgress(progress)
+		_, err := io.Copy(w, br)
+		return err
+	}
+
+	contents, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	decoded, err := huffman.DecodeProgress(contents, progress)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(decoded)
+	return err
+}
+
+func tree(args []string) error {
+	return drawTree("tree", "unicode", args)
+}
+
+func dot(args []string) error {
+	return drawTree("dot", "dot", args)
+}
+
+// treeFormats are the formats drawTree can write a tree in.
+var treeFormats = []string{"dot", "json", "mermaid", "ascii", "unicode"}
+
+func drawTree(name, defaultFormat string, args []string) error {
+	var (
+		inputFile  string
+		outputFile string
+		fromText   bool
+		format     string
+		force      bool
+	)
+	flags := newFlagSet(name, "[-i INPUT-FILE] [-o OUTPUT-FILE] [-f] [--from-text] [--format FORMAT]",
+		"Writes the tree stored in the encoded INPUT-FILE to OUTPUT-FILE. With --from-text\n"+
+			"INPUT-FILE is read as plain input, and the tree that encoding it would use is\n"+
+			"drawn instead.")
+	flags.stringVar(&inputFile, "i", "input", "", "FILE", "read from FILE, - or no file means stdin")
+	flags.stringVar(&outputFile, "o", "output", "", "FILE", "write to FILE, - or no file means stdout")
+	flags.boolVar(&force, "f", "force", "overwrite OUTPUT-FILE if it exists")
+	flags.boolVar(&fromText, "t", "from-text", "build the tree from plain input")
+	flags.stringVar(&format, "", "format", defaultFormat, "FORMAT", fmt.Sprintf("one of %s (default %s)", strings.Join(treeFormats, ", "), defaultFormat))
+	if err := parseNoArgs(flags, args); err != nil {
+		return err
+	}
+	if !slices.Contains(treeFormats, format) {
+		return usageErrorf(name, "unknown format %q, expected one of %s", format, strings.Join(treeFormats, ", "))
+	}
+
+	input, err := openInput(inputFile)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	contents, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+
+	var tree *huffman.Node
+	if fromText {
+		tree = huffman.NewNodeFromInput(contents)
+	} else {
+		tree, err = readTree(contents)
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := createOutput(outputFile, force)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	switch format {
+	case "dot":
+		fmt.Fprintln(w, "digraph G {")
+		huffman.TreeToDot(w, tree)
+		fmt.Fprintln(w, "}")
+	case "json":
+		if err := huffman.TreeToJSON(w, tree); err != nil {
+			return err
+		}
+	case "mermaid":
+		huffman.TreeToMermaid(w, tree)
+	case "ascii":
+		huffman.TreeToASCII(w, tree)
+	case "unicode":
+		huffman.TreeToUnicode(w, tree)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.commit()
+}
+
+// readTree reads the tree out of an encoded file's contents, skipping over its
+// header. An encoded file of empty input has no tree.
+func readTree(contents []byte) (*huffman.Node, error) {
+	if huffman.IsBlockStream(contents) {
+		return nil, fmt.Errorf("error: block streams have a tree per block, decode the stream and use --from-text instead")
+	}
+	_, tree, err := huffman.ReadHeader(huffman.NewBitStringReader(contents))
+	return tree, err
+}
+
+// parseNoArgs parses args into flags, rejecting any positional arguments.
+func parseNoArgs(flags *flagSet, args []string) error {
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return usageErrorf(flags.Name(), "unexpected argument %q", flags.Arg(0))
+	}
+	return nil
+}
+
+// openInput opens the file at path, or stdin when path is empty or "-".
+func openInput(path string) (*os.File, error) {
+	if path == "" || path == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(path)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
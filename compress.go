@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// suffix is appended to the name of every file compressed by name, and
+// stripped again when it is decompressed.
+const suffix = ".huff"
+
+// compressOptions are the gzip style options accepted in place of a command.
+type compressOptions struct {
+	decompress bool
+	keep       bool
+	stdout     bool
+	force      bool
+	progress   bool
+}
+
+func newCompressFlags(o *compressOptions) *flagSet {
+	flags := newFlagSet("", "[-d] [-k] [-c] [-f] [--progress] [FILE...]",
+		"Compresses every FILE into FILE"+suffix+" and removes FILE, as gzip does. With -d\n"+
+			"every FILE"+suffix+" is decompressed back into FILE instead. Encoded files and\n"+
+			"block streams are told apart by their headers. No FILE, or -, means stdin to\n"+
+			"stdout.")
+	flags.boolVar(&o.decompress, "d", "decompress", "decompress instead of compressing")
+	flags.boolVar(&o.keep, "k", "keep", "keep the input files instead of removing them")
+	flags.boolVar(&o.stdout, "c", "stdout", "write to stdout and keep the input files, compressing only takes one FILE")
+	flags.boolVar(&o.force, "f", "force", "overwrite output files, compress files ending in "+suffix+" and write to terminals")
+	flags.boolVar(&o.progress, "", "progress", "draw a progress bar on stderr, if it is a terminal")
+	return flags
+}
+
+// compressFiles runs a gzip style invocation, carrying on past files that fail
+// and exiting with the code of the first failure. Every file must exist before
+// any of them is touched.
+func compressFiles(args []string) int {
+	var o compressOptions
+	flags := newCompressFlags(&o)
+	if err := flags.parse(splitShortFlags(args)); err != nil {
+		return report(err)
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	// encoded files written back to back can't be told apart when decoding
+	if o.stdout && !o.decompress && len(files) > 1 {
+		return report(usageErrorf("", "-c takes a single FILE when compressing, since files encoded back to back cannot be decoded again"))
+	}
+	for _, name := range files {
+		if name == "-" {
+			continue
+		}
+		if _, err := os.Stat(name); err != nil {
+			return report(err)
+		}
+	}
+
+	code := exitOK
+	for _, name := range files {
+		var err error
+		if o.decompress {
+			err = decompressFile(name, o)
+		} else {
+			err = compressFile(name, o)
+		}
+		if err != nil {
+			var pathErr *fs.PathError
+			if name != "-" && !errors.As(err, &pathErr) {
+				err = &namedError{name: name, err: err}
+			}
+			if c := report(err); code == exitOK {
+				code = c
+			}
+		}
+	}
+	return code
+}
+
+// splitShortFlags splits combined short flags, such as the -dc of "gzip -dc",
+// into separate ones.
+func splitShortFlags(args []string) []string {
+	var split []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(split, args[i:]...)
+		}
+		if len(arg) > 2 && arg[0] == '-' && arg[1] != '-' && strings.Trim(arg[1:], "dkcfh") == "" {
+			for _, c := range arg[1:] {
+				split = append(split, "-"+string(c))
+			}
+			continue
+		}
+		split = append(split, arg)
+	}
+	return split
+}
+
+func compressFile(name string, o compressOptions) error {
+	if name != "-" && strings.HasSuffix(name, suffix) && !o.force {
+		return fmt.Errorf("error: already has the %s suffix, skipping it", suffix)
+	}
+	return convertFile(name, name+suffix, o, func(f *outputFile, input *os.File) error {
+		if isTerminal(f.File) && !o.force {
+			return fmt.Errorf("error: refusing to write compressed data to a terminal, use -f to force it")
+		}
+		bar := newProgressBar(o.progress, input)
+		defer bar.close()
+		w := bufio.NewWriter(f)
+		if err := encodeTo(w, input, false, false, bar.callback()); err != nil {
+			return err
+		}
+		bar.finish()
+		return w.Flush()
+	})
+}
+
+func decompressFile(name string, o compressOptions) error {
+	outputName, ok := strings.CutSuffix(name, suffix)
+	if name != "-" && !o.stdout && (!ok || outputName == "") {
+		return fmt.Errorf("error: does not end in %s, use -c to decompress it to stdout", suffix)
+	}
+	return convertFile(name, outputName, o, func(f *outputFile, input *os.File) error {
+		bar := newProgressBar(o.progress, input)
+		defer bar.close()
+		if err := decodeTo(f, input, bar.callback()); err != nil {
+			return err
+		}
+		bar.finish()
+		return nil
+	})
+}
+
+// convertFile reads the file name and writes it to outputName through
+// convert. Unless o says otherwise the output takes over the mode and
+// modification time of name, and name is removed once the output is in place.
+func convertFile(name, outputName string, o compressOptions, convert func(*outputFile, *os.File) error) error {
+	if name == "-" || o.stdout {
+		outputName = "-"
+	}
+
+	input, err := openInput(name)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	var info fs.FileInfo
+	if input != os.Stdin {
+		info, err = input.Stat()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("error: not a regular file, skipping it")
+		}
+	}
+
+	f, err := createOutput(outputName, o.force)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := convert(f, input); err != nil {
+		return err
+	}
+	if err := f.commit(); err != nil {
+		return err
+	}
+	if info == nil || outputName == "-" {
+		return nil
+	}
+
+	if err := os.Chmod(outputName, info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(outputName, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	if o.keep {
+		return nil
+	}
+	input.Close()
+	return os.Remove(name)
+}
+
+// namedError says which of several files err is about.
+type namedError struct {
+	name string
+	err  error
+}
+
+func (e *namedError) Error() string {
+	return e.name + ": " + strings.TrimPrefix(e.err.Error(), "error: ")
+}
+
+func (e *namedError) Unwrap() error {
+	return e.err
+}
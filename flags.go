@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// flagSet wraps flag.FlagSet so that every option can be given a short and a
+// long name, and so that --help lists them together.
+type flagSet struct {
+	*flag.FlagSet
+	synopsis    string
+	description string
+	options     []option
+	args        []string
+}
+
+type option struct {
+	names string
+	help  string
+}
+
+func newFlagSet(name, synopsis, description string) *flagSet {
+	fs := &flagSet{
+		FlagSet:     flag.NewFlagSet(name, flag.ContinueOnError),
+		synopsis:    synopsis,
+		description: description,
+	}
+	// flag prints the usage itself on every parse error, parse takes care of
+	// that instead
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	return fs
+}
+
+func (fs *flagSet) stringVar(p *string, short, long, value, arg, help string) {
+	if short != "" {
+		fs.StringVar(p, short, value, help)
+	}
+	fs.StringVar(p, long, value, help)
+	fs.addOption(short, long, arg, help)
+}
+
+func (fs *flagSet) intVar(p *int, short, long string, value int, arg, help string) {
+	if short != "" {
+		fs.IntVar(p, short, value, help)
+	}
+	fs.IntVar(p, long, value, help)
+	fs.addOption(short, long, arg, fmt.Sprintf("%s (default %d)", help, value))
+}
+
+func (fs *flagSet) boolVar(p *bool, short, long string, help string) {
+	if short != "" {
+		fs.BoolVar(p, short, false, help)
+	}
+	fs.BoolVar(p, long, false, help)
+	fs.addOption(short, long, "", help)
+}
+
+func (fs *flagSet) addOption(short, long, arg, help string) {
+	names := "    --" + long
+	if short != "" {
+		names = "-" + short + ", --" + long
+	}
+	if arg != "" {
+		names += " " + arg
+	}
+	fs.options = append(fs.options, option{names: names, help: help})
+}
+
+func (fs *flagSet) printUsage(w io.Writer) {
+	name := programName
+	if fs.Name() != "" {
+		name += " " + fs.Name()
+	}
+	fmt.Fprintf(w, "usage: %s %s\n\n", name, fs.synopsis)
+	fmt.Fprintf(w, "%s\n", fs.description)
+
+	fmt.Fprintf(w, "\nOptions:\n")
+	fs.printOptions(w)
+}
+
+func (fs *flagSet) printOptions(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, o := range fs.options {
+		fmt.Fprintf(tw, "  %s\t%s\n", o.names, o.help)
+	}
+	fmt.Fprintf(tw, "  -h, --help\tshow this help\n")
+	tw.Flush()
+}
+
+// parse parses args, which must not include the command's name. Flags and
+// positional arguments may be interleaved, everything after "--" is
+// positional. Asking for --help prints the command's usage and returns
+// errHelp.
+func (fs *flagSet) parse(args []string) error {
+	fs.args = nil
+	for {
+		err := fs.Parse(args)
+		if errors.Is(err, flag.ErrHelp) {
+			fs.printUsage(os.Stdout)
+			return errHelp
+		}
+		if err != nil {
+			return usageErrorf(fs.Name(), "%s", err)
+		}
+
+		rest := fs.FlagSet.Args()
+		if len(rest) == 0 {
+			return nil
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			fs.args = append(fs.args, rest...)
+			return nil
+		}
+		fs.args = append(fs.args, rest[0])
+		args = rest[1:]
+	}
+}
+
+// Args returns the positional arguments left after parsing.
+func (fs *flagSet) Args() []string {
+	return fs.args
+}
+
+func (fs *flagSet) NArg() int {
+	return len(fs.args)
+}
+
+func (fs *flagSet) Arg(i int) string {
+	if i < 0 || i >= len(fs.args) {
+		return ""
+	}
+	return fs.args[i]
+}
+
+// errHelp is returned by commands that printed their help instead of running.
+var errHelp = errors.New("help requested")
+
+// usageError is returned when a command was invoked incorrectly.
+type usageError struct {
+	command string
+	message string
+}
+
+func usageErrorf(command, format string, a ...any) error {
+	return &usageError{command: command, message: fmt.Sprintf(format, a...)}
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+// hint tells the user where to find the usage that e is about.
+func (e *usageError) hint() string {
+	if e.command == "" {
+		return fmt.Sprintf("Run '%s --help' for usage.", programName)
+	}
+	return fmt.Sprintf("Run '%s %s --help' for usage.", programName, e.command)
+}
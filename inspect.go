@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mstergianis/huffman/pkg/huffman"
+)
+
+type inspectReport struct {
+	Format         string           `json:"format"`
+	OriginalLength int64            `json:"originalLength"`
+	CompressedSize int64            `json:"compressedSize"`
+	Ratio          float64          `json:"ratio"`
+	Blocks         int              `json:"blocks,omitempty"`
+	TreeDepth      int              `json:"treeDepth,omitempty"`
+	Symbols        int              `json:"symbols,omitempty"`
+	Codes          []inspectCode    `json:"codes,omitempty"`
+	CodeLengths    []inspectLengths `json:"codeLengths,omitempty"`
+}
+
+type inspectCode struct {
+	Symbol byte   `json:"symbol"`
+	Code   string `json:"code"`
+}
+
+type inspectLengths struct {
+	Length  int `json:"length"`
+	Symbols int `json:"symbols"`
+}
+
+func inspect(args []string) error {
+	var (
+		inputFile string
+		asJSON    bool
+	)
+	flags := newFlagSet("inspect", "[-i INPUT-FILE] [--json]",
+		"Describes the encoded INPUT-FILE without decoding it: its lengths, its tree and\n"+
+			"the code of every symbol.")
+	flags.stringVar(&inputFile, "i", "input", "", "FILE", "read from FILE, - or no file means stdin")
+	flags.boolVar(&asJSON, "", "json", "report as JSON")
+	if err := parseNoArgs(flags, args); err != nil {
+		return err
+	}
+
+	input, err := openInput(inputFile)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	contents, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+
+	var report *inspectReport
+	if huffman.IsBlockStream(contents) {
+		report, err = inspectBlockStream(contents)
+	} else {
+		report, err = inspectEncoded(contents)
+	}
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return report.print(os.Stdout)
+}
+
+func inspectEncoded(contents []byte) (*inspectReport, error) {
+	contentLength, tree, err := huffman.ReadHeader(huffman.NewBitStringReader(contents))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &inspectReport{
+		Format:         "encoded",
+		OriginalLength: int64(contentLength),
+		CompressedSize: int64(len(contents)),
+	}
+	if contentLength == huffman.UnknownContentLength {
+		// the length is only known once the content has been decoded up to
+		// the end of block symbol
+		report.Format = "encoded, ended by an end of block symbol"
+		report.OriginalLength = -1
+	}
+	report.setRatio()
+	if tree == nil {
+		return report, nil
+	}
+
+	histogram := map[int]int{}
+	for symbol, code := range tree.Codes() {
+		report.Codes = append(report.Codes, inspectCode{Symbol: symbol, Code: code})
+		report.TreeDepth = max(report.TreeDepth, len(code))
+		histogram[len(code)]++
+	}
+	report.Symbols = len(report.Codes)
+	slices.SortFunc(report.Codes, func(a, b inspectCode) int {
+		return cmp.Or(cmp.Compare(len(a.Code), len(b.Code)), cmp.Compare(a.Symbol, b.Symbol))
+	})
+	for length, symbols := range histogram {
+		report.CodeLengths = append(report.CodeLengths, inspectLengths{Length: length, Symbols: symbols})
+	}
+	slices.SortFunc(report.CodeLengths, func(a, b inspectLengths) int {
+		return cmp.Compare(a.Length, b.Length)
+	})
+
+	return report, nil
+}
+
+func inspectBlockStream(contents []byte) (*inspectReport, error) {
+	ra, err := huffman.OpenReaderAt(bytes.NewReader(contents), int64(len(contents)))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &inspectReport{
+		Format:         "block stream",
+		OriginalLength: ra.Size(),
+		CompressedSize: int64(len(contents)),
+		Blocks:         ra.NumBlocks(),
+	}
+	report.setRatio()
+	return report, nil
+}
+
+func (r *inspectReport) setRatio() {
+	if r.OriginalLength > 0 {
+		r.Ratio = float64(r.CompressedSize) / float64(r.OriginalLength)
+	}
+}
+
+func (r *inspectReport) print(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "format:\t%s\n", r.Format)
+	if r.OriginalLength < 0 {
+		fmt.Fprintf(w, "original length:\tunknown\n")
+		fmt.Fprintf(w, "compressed size:\t%d bytes\n", r.CompressedSize)
+		fmt.Fprintf(w, "ratio:\tunknown\n")
+	} else {
+		fmt.Fprintf(w, "original length:\t%d bytes\n", r.OriginalLength)
+		fmt.Fprintf(w, "compressed size:\t%d bytes\n", r.CompressedSize)
+		fmt.Fprintf(w, "ratio:\t%.3f\n", r.Ratio)
+	}
+	if r.Blocks > 0 {
+		fmt.Fprintf(w, "blocks:\t%d\n", r.Blocks)
+	}
+	if r.Symbols > 0 {
+		fmt.Fprintf(w, "tree depth:\t%d\n", r.TreeDepth)
+		fmt.Fprintf(w, "symbols:\t%d\n", r.Symbols)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.CodeLengths) > 0 {
+		fmt.Fprintf(w, "\ncode lengths:\n")
+		for _, l := range r.CodeLengths {
+			fmt.Fprintf(w, "  %d\t%d\t%s\n", l.Length, l.Symbols, strings.Repeat("#", l.Symbols))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Codes) > 0 {
+		fmt.Fprintf(w, "\ncodes:\n")
+		for _, c := range r.Codes {
+			fmt.Fprintf(w, "  %q\t%s\n", string([]byte{c.Symbol}), c.Code)
+		}
+	}
+	return w.Flush()
+}
@@ -3,111 +3,164 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 
 	"github.com/mstergianis/huffman/pkg/huffman"
 )
 
 var programName string
 
+// exit codes
+const (
+	exitOK        = 0
+	exitFailure   = 1
+	exitUsage     = 2
+	exitIO        = 3
+	exitCorrupt   = 4
+	exitIntegrity = 5
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "encode", summary: "encode a file", run: encode},
+		{name: "decode", summary: "decode an encoded file or block stream", run: decode},
+		{name: "verify", summary: "check that encoded files decode correctly", run: verify},
+		{name: "inspect", summary: "describe an encoded file without decoding it", run: inspect},
+		{name: "codes", summary: "print the code of every symbol in a tree", run: codes},
+		{name: "tree", summary: "draw the tree of an encoded file or of plain input", run: tree},
+		{name: "dot", summary: "the tree command, writing a graphviz digraph by default", run: dot},
+		{name: "pack", summary: "pack files and directories into an archive", run: pack},
+		{name: "list", summary: "list the entries of an archive", run: list},
+		{name: "unpack", summary: "unpack an archive into a directory", run: unpack},
+		{name: "bench", summary: "measure compression ratio and throughput", run: bench},
+		{name: "help", summary: "show help for a command", run: help},
+	}
+}
+
 func main() {
-	args := os.Args
-	var err error
-	programName, err = shift(&args)
-	check(err)
-	operatingMode, err := shift(&args)
-	check(err)
-	var (
-		inputFile  string
-		outputFile string
-	)
-	for len(args) > 0 {
-		arg, err := shift(&args)
-		check(err)
-		switch arg {
-		case "-i":
-			inputFile, err = shift(&args)
-			check(err)
-		case "-o":
-			outputFile, err = shift(&args)
-			check(err)
-		default:
-			usage()
+	programName = filepath.Base(os.Args[0])
+	removeOnInterrupt()
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) < 1 {
+		// without arguments data piped in is compressed, as gzip does
+		if !isTerminal(os.Stdin) {
+			return compressFiles(args)
 		}
+		usage()
+		return exitUsage
 	}
 
-	input, err := os.ReadFile(inputFile)
-	check(err)
+	name := args[0]
+	if name == "-h" || name == "--help" {
+		usage()
+		return exitOK
+	}
 
-	var modeFunc func([]byte) ([]byte, error)
-	switch operatingMode {
-	case "encode":
-		{
-			modeFunc = huffman.Encode
-		}
-	case "decode":
-		{
-			modeFunc = huffman.Decode
-		}
-	case "dot":
-		{
-			bsr := huffman.NewBitStringReader(input)
-			tree := huffman.NewNodeFromBytes(bsr)
-
-			f, err := os.OpenFile(outputFile, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
-			check(err)
-			defer f.Close()
-
-			fmt.Fprintln(f, "digraph G {")
-			huffman.TreeToDot(f, tree)
-			fmt.Fprintln(f, "}")
-			return
+	// an option or an existing file in place of a command is a gzip style
+	// invocation, as in "huff -d file.txt.huff", while anything else is most
+	// likely a misspelled command that must not touch the files after it
+	cmd := findCommand(name)
+	if cmd == nil {
+		if _, err := os.Lstat(name); err != nil && !strings.HasPrefix(name, "-") {
+			return report(usageErrorf("", "unknown command %q", name))
 		}
+		return compressFiles(args)
 	}
 
-	f, err := os.OpenFile(outputFile, os.O_CREATE|os.O_RDWR, 0644)
-	check(err)
-	defer f.Close()
+	return report(cmd.run(args[1:]))
+}
 
-	contents, err := modeFunc(input)
-	check(err)
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
 
-	n, err := f.Write(contents)
-	check(err)
-	if n < len(contents) {
-		panic(fmt.Sprintf("error: wrote fewer bytes (%d) than the content's length (%d)", n, len(contents)))
+// report prints err, if there is one, and returns the exit code that describes
+// it.
+func report(err error) int {
+	if err == nil || errors.Is(err, errHelp) {
+		return exitOK
 	}
 
-	return
+	var (
+		usageErr    *usageError
+		checksumErr *huffman.ChecksumError
+		corruptErr  *huffman.CorruptInputError
+		pathErr     *fs.PathError
+	)
+	// the library prefixes its errors, which reads poorly after the program's
+	// name
+	message := strings.TrimPrefix(err.Error(), "error: ")
+	switch {
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(os.Stderr, "%s: %s\n%s\n", programName, message, usageErr.hint())
+		return exitUsage
+	case errors.As(err, &checksumErr), errors.Is(err, huffman.ErrVerifyMismatch):
+		fmt.Fprintf(os.Stderr, "%s: integrity check failed: %s\n", programName, message)
+		return exitIntegrity
+	case errors.As(err, &corruptErr):
+		fmt.Fprintf(os.Stderr, "%s: corrupt input: %s\n", programName, message)
+		return exitCorrupt
+	case errors.As(err, &pathErr), errors.Is(err, syscall.EPIPE):
+		fmt.Fprintf(os.Stderr, "%s: %s\n", programName, message)
+		return exitIO
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: %s\n", programName, message)
+	return exitFailure
 }
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "usage: %s COMMAND\n", programName)
+	flags := newCompressFlags(&compressOptions{})
+	fmt.Fprintf(os.Stderr, "usage: %s COMMAND [OPTIONS]\n", programName)
+	fmt.Fprintf(os.Stderr, "       %s %s\n\n", programName, flags.synopsis)
 	fmt.Fprintf(os.Stderr, "Available commands:\n")
-	fmt.Fprintf(os.Stderr, "    encode -i INPUT-FILE -o OUTPUT-FILE\n")
-	fmt.Fprintf(os.Stderr, "    decode -i INPUT-FILE -o OUTPUT-FILE\n")
-	os.Exit(1)
+	w := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "    %s\t%s\n", cmd.name, cmd.summary)
+	}
+	w.Flush()
+	fmt.Fprintf(os.Stderr, "\nRun '%s COMMAND --help' for the options of a command.\n", programName)
+	fmt.Fprintf(os.Stderr, "\n%s\n", flags.description)
+	fmt.Fprintf(os.Stderr, "\nOptions without a command:\n")
+	flags.printOptions(os.Stderr)
+	fmt.Fprintf(os.Stderr, "\nExit codes:\n")
+	fmt.Fprintf(os.Stderr, "    %d  success\n", exitOK)
+	fmt.Fprintf(os.Stderr, "    %d  failure\n", exitFailure)
+	fmt.Fprintf(os.Stderr, "    %d  usage error\n", exitUsage)
+	fmt.Fprintf(os.Stderr, "    %d  I/O error\n", exitIO)
+	fmt.Fprintf(os.Stderr, "    %d  corrupt input\n", exitCorrupt)
+	fmt.Fprintf(os.Stderr, "    %d  integrity check failed\n", exitIntegrity)
 }
 
-func check(err error) {
-	if errors.Is(err, shiftErr) {
-		fmt.Fprintf(os.Stderr, "%s\n", err)
+func help(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" {
 		usage()
+		return nil
 	}
-	if err != nil {
-		panic(err.Error())
-	}
-}
-
-var (
-	shiftErr = errors.New("error: expected an argument but did not find one")
-)
-
-func shift(ss *[]string) (string, error) {
-	if len(*ss) < 1 {
-		return "", shiftErr
+	cmd := findCommand(args[0])
+	if cmd == nil || cmd.name == "help" {
+		return usageErrorf("help", "unknown command %q", args[0])
 	}
-	s := (*ss)[0]
-	*ss = (*ss)[1:]
-	return s, nil
+	return cmd.run([]string{"--help"})
 }
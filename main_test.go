@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mstergianis/huffman/pkg/huffman"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunUnknownCommand(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "out.txt")
+	assert.NoError(t, os.WriteFile(name, []byte("hello\n"), 0o644))
+
+	t.Run("misspelled command", func(t *testing.T) {
+		Equal(t, exitUsage, run([]string{"encdoe", name}))
+		content, err := os.ReadFile(name)
+		assert.NoError(t, err)
+		Equal(t, "hello\n", string(content))
+		assert.NoFileExists(t, name+suffix)
+	})
+
+	t.Run("missing operand", func(t *testing.T) {
+		Equal(t, exitIO, run([]string{name, filepath.Join(dir, "nothere")}))
+		assert.FileExists(t, name)
+		assert.NoFileExists(t, name+suffix)
+	})
+}
+
+func TestRunCompressToStdout(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	assert.NoError(t, os.WriteFile(a, []byte("a\n"), 0o644))
+	assert.NoError(t, os.WriteFile(b, []byte("b\n"), 0o644))
+
+	// decode could only read back the first of several encoded files
+	Equal(t, exitUsage, run([]string{"-c", a, b}))
+	Equal(t, exitUsage, run([]string{"-kc", a, b}))
+	assert.FileExists(t, a)
+	assert.FileExists(t, b)
+}
+
+func TestUnpack(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "tree.huffa")
+	dest := filepath.Join(dir, "dest")
+	sub := filepath.Join(dest, "tree", "sub")
+	file := filepath.Join(sub, "x")
+	// the read-only directory would keep the temporary directory from being
+	// removed
+	t.Cleanup(func() { os.Chmod(sub, 0755) })
+
+	f, err := os.Create(archive)
+	assert.NoError(t, err)
+	modTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	aw := huffman.NewArchiveWriter(f)
+	assert.NoError(t, aw.Add(huffman.ArchiveEntry{Path: "tree", Mode: fs.ModeDir | 0755, ModTime: modTime}, nil))
+	assert.NoError(t, aw.Add(huffman.ArchiveEntry{Path: "tree/sub", Mode: fs.ModeDir | 0555, ModTime: modTime}, nil))
+	assert.NoError(t, aw.Add(huffman.ArchiveEntry{Path: "tree/sub/x", Mode: 0640, ModTime: modTime, Size: 6}, strings.NewReader("hello\n")))
+	assert.NoError(t, aw.Close())
+	assert.NoError(t, f.Close())
+
+	t.Run("read-only directory", func(t *testing.T) {
+		Equal(t, exitOK, run([]string{"unpack", archive, "-C", dest}))
+		info, err := os.Stat(sub)
+		assert.NoError(t, err)
+		Equal(t, fs.FileMode(0555), info.Mode().Perm())
+		Equal(t, true, modTime.Equal(info.ModTime()))
+
+		content, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		Equal(t, "hello\n", string(content))
+		info, err = os.Stat(file)
+		assert.NoError(t, err)
+		Equal(t, fs.FileMode(0640), info.Mode().Perm())
+	})
+
+	t.Run("existing files", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(file, []byte("changed\n"), 0600))
+		assert.NoError(t, os.Chmod(file, 0600))
+
+		Equal(t, exitIO, run([]string{"unpack", archive, "-C", dest}))
+		content, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		Equal(t, "changed\n", string(content))
+
+		Equal(t, exitOK, run([]string{"unpack", "-f", archive, "-C", dest}))
+		content, err = os.ReadFile(file)
+		assert.NoError(t, err)
+		Equal(t, "hello\n", string(content))
+		info, err := os.Stat(file)
+		assert.NoError(t, err)
+		Equal(t, fs.FileMode(0640), info.Mode().Perm())
+	})
+}
+
+func Equal[E any](t assert.TestingT, expected, actual E, msgAndArgs ...any) bool {
+	return assert.Equal(t, expected, actual, msgAndArgs...)
+}
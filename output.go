@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// outputFile is a file being written by a command. Output to a named file goes
+// to a temporary file next to it, which only replaces the named file once it
+// has been written in full, so that a failed or interrupted command never
+// leaves a partial file behind.
+type outputFile struct {
+	*os.File
+	path string
+	temp bool
+	done bool
+	// replaced is the file at path when the output was created, if there was
+	// one
+	replaced fs.FileInfo
+}
+
+// createOutput creates the output for path, which is stdout when path is empty
+// or "-". An existing file at path is refused unless force is set.
+func createOutput(path string, force bool) (*outputFile, error) {
+	if path == "" || path == "-" {
+		return &outputFile{File: os.Stdout, path: "-"}, nil
+	}
+
+	replaced, err := os.Lstat(path)
+	if err == nil && !force {
+		return nil, &fs.PathError{Op: "create", Path: path, Err: errors.New("file already exists, use -f to overwrite it")}
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	f, err := createTemp(filepath.Dir(path), "."+filepath.Base(path)+".", ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	pendingOutputs.add(f.Name())
+	return &outputFile{File: f, path: path, temp: true, replaced: replaced}, nil
+}
+
+// createTemp creates a new file in dir named prefix, a random number and
+// suffix. Unlike os.CreateTemp it creates the file with mode 0666 less the
+// umask, as os.Create does, since the file is renamed into place rather than
+// copied.
+func createTemp(dir, prefix, suffix string) (*os.File, error) {
+	for range 10000 {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix)
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+	return nil, &fs.PathError{Op: "createtemp", Path: filepath.Join(dir, prefix+"*"+suffix), Err: fs.ErrExist}
+}
+
+// commit moves the written output into place.
+func (o *outputFile) commit() error {
+	if !o.temp {
+		return nil
+	}
+	o.done = true
+	defer pendingOutputs.remove(o.Name())
+
+	// a replaced file keeps its mode
+	var err error
+	if o.replaced != nil && o.replaced.Mode().IsRegular() {
+		err = o.Chmod(o.replaced.Mode().Perm())
+	}
+	if err == nil {
+		err = o.Sync()
+	}
+	if closeErr := o.File.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(o.Name(), o.path)
+	}
+	if err != nil {
+		os.Remove(o.Name())
+	}
+	return err
+}
+
+// Close discards the output unless it was committed. It is safe to call more
+// than once, and meant to be deferred.
+func (o *outputFile) Close() error {
+	if !o.temp || o.done {
+		return nil
+	}
+	o.done = true
+	o.File.Close()
+	pendingOutputs.remove(o.Name())
+	return os.Remove(o.Name())
+}
+
+// pendingOutputs are the temporary files that have yet to be committed, which
+// are removed if the program is interrupted.
+var pendingOutputs = &tempFiles{paths: map[string]struct{}{}}
+
+type tempFiles struct {
+	sync.Mutex
+	paths map[string]struct{}
+}
+
+func (t *tempFiles) add(path string) {
+	t.Lock()
+	defer t.Unlock()
+	t.paths[path] = struct{}{}
+}
+
+func (t *tempFiles) remove(path string) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.paths, path)
+}
+
+// removeOnInterrupt removes every pending output and exits when the program
+// is interrupted or terminated.
+func removeOnInterrupt() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		pendingOutputs.Lock()
+		for path := range pendingOutputs.paths {
+			os.Remove(path)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s\n", programName, sig)
+		os.Exit(128 + int(sig.(syscall.Signal)))
+	}()
+}
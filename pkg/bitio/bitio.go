@@ -0,0 +1,40 @@
+// Package bitio reads and writes streams of bits.
+//
+// A Writer packs bits into bytes written to an io.Writer, and a Reader unpacks
+// them from an io.Reader. Both pack bits in one of two orders: MSBFirst, which
+// fills every byte from its most significant bit down, or LSBFirst, which fills
+// it from its least significant bit up, as DEFLATE does. Reader and Writer must
+// agree on the order for a stream to round-trip.
+//
+// Values of up to 64 bits can be written at once, and values of up to
+// MaxReadBits read or peeked at once. Both types keep track of their position
+// in bits, can align it to a byte boundary, and copy whole bytes through their
+// Write and Read methods, which makes them an io.Writer and an io.Reader.
+//
+// Integers of no fixed width can be written in the Elias gamma and delta,
+// Exp-Golomb and Golomb-Rice codes, or as LEB128 varints.
+package bitio
+
+import "fmt"
+
+// BitOrder is the order bits are packed into bytes in.
+type BitOrder int
+
+const (
+	// MSBFirst fills every byte from its most significant bit down, and
+	// writes values most significant bit first.
+	MSBFirst BitOrder = iota
+	// LSBFirst fills every byte from its least significant bit up, and
+	// writes values least significant bit first.
+	LSBFirst
+)
+
+func (o BitOrder) String() string {
+	switch o {
+	case MSBFirst:
+		return "MSBFirst"
+	case LSBFirst:
+		return "LSBFirst"
+	}
+	return fmt.Sprintf("BitOrder(%d)", int(o))
+}
@@ -0,0 +1,104 @@
+package bitio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitOrderString(t *testing.T) {
+	Equal(t, "MSBFirst", MSBFirst.String())
+	Equal(t, "LSBFirst", LSBFirst.String())
+	Equal(t, "BitOrder(7)", BitOrder(7).String())
+}
+
+func TestRoundTrip(t *testing.T) {
+	// every width is written after every number of bits that leaves the
+	// stream at each alignment, then read back
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		t.Run(order.String(), func(t *testing.T) {
+			for offset := range 8 {
+				for width := 1; width <= 64; width++ {
+					value := uint64(width)*0x9e37_79b9_7f4a_7c15 | 1<<(width-1)
+					buf := &bytes.Buffer{}
+					bw := NewWriterOrder(buf, order)
+					assert.NoError(t, bw.WriteBits(0b1010_101, offset))
+					assert.NoError(t, bw.WriteBits(value, width))
+					assert.NoError(t, bw.WriteBit(1))
+					assert.NoError(t, bw.Flush())
+
+					br := NewReaderOrder(bytes.NewReader(buf.Bytes()), order)
+					prefix, err := br.ReadBits(offset)
+					assert.NoError(t, err)
+					Equal(t, uint64(0b1010_101)&(1<<offset-1), prefix)
+
+					var read uint64
+					if width <= MaxReadBits {
+						read, err = br.ReadBits(width)
+						assert.NoError(t, err)
+					} else {
+						// wider values are read in two parts, in the order
+						// they were packed
+						rest := width - 32
+						first, err := br.ReadBits(32)
+						assert.NoError(t, err)
+						second, err := br.ReadBits(rest)
+						assert.NoError(t, err)
+						if order == LSBFirst {
+							read = second<<32 | first
+						} else {
+							read = first<<rest | second
+						}
+					}
+					Equal(t, value&(1<<width-1), read, "offset %d width %d", offset, width)
+					Equal(t, int64(offset+width), br.BitsRead())
+
+					last, err := br.ReadBit()
+					assert.NoError(t, err)
+					Equal(t, byte(1), last)
+					assert.NoError(t, br.AlignToByte())
+					Equal(t, bw.BitsWritten(), br.BitsRead())
+					_, err = br.ReadBit()
+					Equal(t, io.EOF, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundTripBytes(t *testing.T) {
+	payload := make([]byte, 3*writerBufferSize+5)
+	for i := range payload {
+		payload[i] = byte(i * 7)
+	}
+	for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+		for offset := range 8 {
+			buf := &bytes.Buffer{}
+			bw := NewWriterOrder(buf, order)
+			assert.NoError(t, bw.WriteBits(0, offset))
+			n, err := bw.Write(payload)
+			assert.NoError(t, err)
+			Equal(t, len(payload), n)
+			assert.NoError(t, bw.Flush())
+
+			br := NewReaderOrder(bytes.NewReader(buf.Bytes()), order)
+			assert.NoError(t, br.Skip(int64(offset)))
+			read := make([]byte, len(payload))
+			_, err = io.ReadFull(br, read)
+			assert.NoError(t, err)
+			Equal(t, payload, read, "%s offset %d", order, offset)
+		}
+	}
+}
+
+func Equal[E any](t assert.TestingT, expected, actual E, msgAndArgs ...any) bool {
+	return assert.Equal(t, expected, actual, msgAndArgs...)
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
@@ -0,0 +1,269 @@
+package bitio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathbits "math/bits"
+)
+
+// Universal codes write integers in as few bits as their magnitude needs, with
+// no fixed width agreed up front. Every code starts with a unary part, a run of
+// zeroes ended by a one, followed by plain bits written with WriteBits. In
+// MSBFirst order that gives the textbook bit sequences, in LSBFirst the plain
+// bits are packed as WriteBits packs them and so come out reversed.
+//
+// Reading a code returns io.EOF only when the input ends before its first bit,
+// and io.ErrUnexpectedEOF when it ends part way through.
+
+// errOverflow is returned when a code read from the input does not fit in 64
+// bits.
+var errOverflow = errors.New("error: universal code overflows a 64 bit integer")
+
+// WriteUnary writes n as n zeroes followed by a one.
+func (bw *Writer) WriteUnary(n uint64) error {
+	for ; n >= 64; n -= 64 {
+		if err := bw.WriteBits(0, 64); err != nil {
+			return err
+		}
+	}
+	if err := bw.WriteBits(0, int(n)); err != nil {
+		return err
+	}
+	return bw.WriteBit(1)
+}
+
+// ReadUnary reads a number written with WriteUnary.
+func (br *Reader) ReadUnary() (uint64, error) {
+	var n uint64
+	for {
+		if br.n == 0 {
+			br.fill(MaxReadBits)
+		}
+		if br.n == 0 {
+			if br.err == io.EOF && n > 0 {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, br.err
+		}
+
+		var zeroes int
+		if br.order == LSBFirst {
+			zeroes = mathbits.TrailingZeros64(br.acc)
+		} else {
+			zeroes = mathbits.LeadingZeros64(br.acc)
+		}
+		if zeroes < br.n {
+			br.consume(zeroes + 1)
+			return n + uint64(zeroes), nil
+		}
+		n += uint64(br.n)
+		br.consume(br.n)
+	}
+}
+
+// WriteGamma writes v, which must be at least 1, in the Elias gamma code: the
+// number of bits after v's leading one in unary, then those bits.
+func (bw *Writer) WriteGamma(v uint64) error {
+	if v == 0 {
+		return fmt.Errorf("error: the Elias gamma code cannot represent 0")
+	}
+	n := mathbits.Len64(v) - 1
+	if err := bw.WriteUnary(uint64(n)); err != nil {
+		return err
+	}
+	return bw.WriteBits(v, n)
+}
+
+// ReadGamma reads a number written with WriteGamma.
+func (br *Reader) ReadGamma() (uint64, error) {
+	n, err := br.ReadUnary()
+	if err != nil {
+		return 0, err
+	}
+	if n > 63 {
+		return 0, errOverflow
+	}
+	low, err := br.readWide(int(n))
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	return 1<<n | low, nil
+}
+
+// WriteDelta writes v, which must be at least 1, in the Elias delta code: the
+// length of v in the gamma code, then the bits after v's leading one.
+func (bw *Writer) WriteDelta(v uint64) error {
+	if v == 0 {
+		return fmt.Errorf("error: the Elias delta code cannot represent 0")
+	}
+	n := mathbits.Len64(v) - 1
+	if err := bw.WriteGamma(uint64(n + 1)); err != nil {
+		return err
+	}
+	return bw.WriteBits(v, n)
+}
+
+// ReadDelta reads a number written with WriteDelta.
+func (br *Reader) ReadDelta() (uint64, error) {
+	length, err := br.ReadGamma()
+	if err != nil {
+		return 0, err
+	}
+	if length > 64 {
+		return 0, errOverflow
+	}
+	n := int(length - 1)
+	low, err := br.readWide(n)
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	return 1<<n | low, nil
+}
+
+// WriteExpGolomb writes v in the Exp-Golomb code of order k, for k up to 64:
+// v's bits above the low k in the gamma code, offset by one so that 0 can be
+// written, then the low k bits.
+func (bw *Writer) WriteExpGolomb(v uint64, k int) error {
+	if k < 0 || k > 64 {
+		return fmt.Errorf("error: Exp-Golomb order %d is out of range, expected 0 to 64", k)
+	}
+	q := v >> k
+	if q == math.MaxUint64 {
+		return fmt.Errorf("error: %d is too large for the Exp-Golomb code of order %d", v, k)
+	}
+	if err := bw.WriteGamma(q + 1); err != nil {
+		return err
+	}
+	return bw.WriteBits(v, k)
+}
+
+// ReadExpGolomb reads a number written with WriteExpGolomb with the same order.
+func (br *Reader) ReadExpGolomb(k int) (uint64, error) {
+	if k < 0 || k > 64 {
+		return 0, fmt.Errorf("error: Exp-Golomb order %d is out of range, expected 0 to 64", k)
+	}
+	q, err := br.ReadGamma()
+	if err != nil {
+		return 0, err
+	}
+	return br.readRemainder(q-1, k)
+}
+
+// WriteRice writes v in the Golomb-Rice code with parameter k, for k up to 64:
+// v's bits above the low k in unary, then the low k bits. The unary part grows
+// with v >> k, so k should suit the values written.
+func (bw *Writer) WriteRice(v uint64, k int) error {
+	if k < 0 || k > 64 {
+		return fmt.Errorf("error: Golomb-Rice parameter %d is out of range, expected 0 to 64", k)
+	}
+	if err := bw.WriteUnary(v >> k); err != nil {
+		return err
+	}
+	return bw.WriteBits(v, k)
+}
+
+// ReadRice reads a number written with WriteRice with the same parameter.
+func (br *Reader) ReadRice(k int) (uint64, error) {
+	if k < 0 || k > 64 {
+		return 0, fmt.Errorf("error: Golomb-Rice parameter %d is out of range, expected 0 to 64", k)
+	}
+	q, err := br.ReadUnary()
+	if err != nil {
+		return 0, err
+	}
+	return br.readRemainder(q, k)
+}
+
+// readRemainder reads the low k bits of a number whose bits above them are q.
+func (br *Reader) readRemainder(q uint64, k int) (uint64, error) {
+	if q > math.MaxUint64>>k {
+		return 0, errOverflow
+	}
+	low, err := br.readWide(k)
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	return q<<k | low, nil
+}
+
+// WriteUvarint writes v as an unsigned LEB128 varint: 7 bits at a time, low
+// bits first, in 8 bit groups whose top bit is set on all but the last. On a
+// byte boundary in MSBFirst order the bytes are those of
+// binary.AppendUvarint.
+func (bw *Writer) WriteUvarint(v uint64) error {
+	for v >= 0x80 {
+		if err := bw.WriteBits(v&0x7f|0x80, 8); err != nil {
+			return err
+		}
+		v >>= 7
+	}
+	return bw.WriteBits(v, 8)
+}
+
+// ReadUvarint reads a number written with WriteUvarint.
+func (br *Reader) ReadUvarint() (uint64, error) {
+	var v uint64
+	for i := 0; i < 10; i++ {
+		group, err := br.ReadBits(8)
+		if err != nil {
+			if i > 0 {
+				err = unexpected(err)
+			}
+			return 0, err
+		}
+		if i == 9 && group > 1 {
+			return 0, errOverflow
+		}
+		v |= (group & 0x7f) << (7 * i)
+		if group < 0x80 {
+			return v, nil
+		}
+	}
+	return 0, errOverflow
+}
+
+// WriteVarint writes v as a varint, zig-zag encoded as binary.AppendVarint
+// does so that small negative numbers stay short.
+func (bw *Writer) WriteVarint(v int64) error {
+	return bw.WriteUvarint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+// ReadVarint reads a number written with WriteVarint.
+func (br *Reader) ReadVarint() (int64, error) {
+	u, err := br.ReadUvarint()
+	if err != nil {
+		return 0, err
+	}
+	return int64(u>>1) ^ -int64(u&1), nil
+}
+
+// readWide reads n bits, for n up to 64, in two reads when n is more than
+// MaxReadBits.
+func (br *Reader) readWide(n int) (uint64, error) {
+	if n <= MaxReadBits {
+		return br.ReadBits(n)
+	}
+	first, err := br.ReadBits(32)
+	if err != nil {
+		return 0, err
+	}
+	second, err := br.ReadBits(n - 32)
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	if br.order == LSBFirst {
+		return second<<32 | first, nil
+	}
+	return first<<(n-32) | second, nil
+}
+
+// unexpected turns io.EOF into io.ErrUnexpectedEOF, for input that ends part
+// way through a code.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,231 @@
+package bitio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bitString returns the bits written by write, in MSBFirst order, as a string
+// of '0's and '1's.
+func bitString(t *testing.T, write func(bw *Writer) error) string {
+	buf := &bytes.Buffer{}
+	bw := NewWriter(buf)
+	assert.NoError(t, write(bw))
+	n := bw.BitsWritten()
+	assert.NoError(t, bw.Flush())
+
+	s := &strings.Builder{}
+	for i := range n {
+		s.WriteByte('0' + buf.Bytes()[i/8]>>(7-i%8)&1)
+	}
+	return s.String()
+}
+
+func TestCodeBits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		write    func(bw *Writer) error
+		expected string
+	}{
+		{name: "unary 0", write: func(bw *Writer) error { return bw.WriteUnary(0) }, expected: "1"},
+		{name: "unary 3", write: func(bw *Writer) error { return bw.WriteUnary(3) }, expected: "0001"},
+		{name: "gamma 1", write: func(bw *Writer) error { return bw.WriteGamma(1) }, expected: "1"},
+		{name: "gamma 2", write: func(bw *Writer) error { return bw.WriteGamma(2) }, expected: "010"},
+		{name: "gamma 5", write: func(bw *Writer) error { return bw.WriteGamma(5) }, expected: "00101"},
+		{name: "gamma 17", write: func(bw *Writer) error { return bw.WriteGamma(17) }, expected: "000010001"},
+		{name: "delta 1", write: func(bw *Writer) error { return bw.WriteDelta(1) }, expected: "1"},
+		{name: "delta 2", write: func(bw *Writer) error { return bw.WriteDelta(2) }, expected: "0100"},
+		{name: "delta 10", write: func(bw *Writer) error { return bw.WriteDelta(10) }, expected: "00100010"},
+		{name: "exp-golomb 0", write: func(bw *Writer) error { return bw.WriteExpGolomb(0, 0) }, expected: "1"},
+		{name: "exp-golomb 3", write: func(bw *Writer) error { return bw.WriteExpGolomb(3, 0) }, expected: "00100"},
+		{name: "exp-golomb order 2", write: func(bw *Writer) error { return bw.WriteExpGolomb(9, 2) }, expected: "01101"},
+		{name: "rice 0", write: func(bw *Writer) error { return bw.WriteRice(0, 2) }, expected: "100"},
+		{name: "rice 9", write: func(bw *Writer) error { return bw.WriteRice(9, 2) }, expected: "00101"},
+		{name: "rice without remainder", write: func(bw *Writer) error { return bw.WriteRice(3, 0) }, expected: "0001"},
+		{name: "uvarint 1", write: func(bw *Writer) error { return bw.WriteUvarint(1) }, expected: "00000001"},
+		{name: "uvarint 300", write: func(bw *Writer) error { return bw.WriteUvarint(300) }, expected: "1010110000000010"},
+		{name: "varint -1", write: func(bw *Writer) error { return bw.WriteVarint(-1) }, expected: "00000001"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Equal(t, tc.expected, bitString(t, tc.write))
+		})
+	}
+}
+
+func TestUvarintMatchesBinary(t *testing.T) {
+	for _, v := range []uint64{0, 1, 127, 128, 300, 1 << 35, math.MaxUint64} {
+		buf := &bytes.Buffer{}
+		bw := NewWriter(buf)
+		assert.NoError(t, bw.WriteUvarint(v))
+		assert.NoError(t, bw.WriteVarint(-int64(v>>1)))
+		assert.NoError(t, bw.Flush())
+
+		expected := binary.AppendUvarint(nil, v)
+		expected = binary.AppendVarint(expected, -int64(v>>1))
+		Equal(t, expected, buf.Bytes(), "%d", v)
+	}
+}
+
+// code writes and reads back one of the universal codes.
+type code struct {
+	name   string
+	write  func(bw *Writer, v uint64) error
+	read   func(br *Reader) (uint64, error)
+	values []uint64
+}
+
+func universalCodes() []code {
+	edges := []uint64{1, 2, 3, 7, 8, 9, 1000, 1 << 31, 1<<32 + 1, 1 << 56, 1<<57 + 3, 1 << 63, math.MaxUint64 - 1, math.MaxUint64}
+	withZero := append([]uint64{0}, edges...)
+	small := []uint64{0, 1, 2, 3, 4, 5, 63, 64, 65, 200, 1000}
+
+	codes := []code{
+		{
+			name:   "unary",
+			write:  (*Writer).WriteUnary,
+			read:   (*Reader).ReadUnary,
+			values: append(small, 1000, 4096),
+		},
+		{
+			name:   "gamma",
+			write:  (*Writer).WriteGamma,
+			read:   (*Reader).ReadGamma,
+			values: edges,
+		},
+		{
+			name:   "delta",
+			write:  (*Writer).WriteDelta,
+			read:   (*Reader).ReadDelta,
+			values: edges,
+		},
+		{
+			name:   "uvarint",
+			write:  (*Writer).WriteUvarint,
+			read:   (*Reader).ReadUvarint,
+			values: withZero,
+		},
+		{
+			name:  "varint",
+			write: func(bw *Writer, v uint64) error { return bw.WriteVarint(int64(v)) },
+			read: func(br *Reader) (uint64, error) {
+				v, err := br.ReadVarint()
+				return uint64(v), err
+			},
+			values: withZero,
+		},
+	}
+	for _, k := range []int{0, 1, 5, 63, 64} {
+		values := withZero
+		if k == 0 {
+			values = withZero[:len(withZero)-1]
+		}
+		codes = append(codes, code{
+			name:   fmt.Sprintf("exp-golomb order %d", k),
+			write:  func(bw *Writer, v uint64) error { return bw.WriteExpGolomb(v, k) },
+			read:   func(br *Reader) (uint64, error) { return br.ReadExpGolomb(k) },
+			values: values,
+		})
+	}
+	for _, k := range []int{0, 3, 60, 64} {
+		values := small
+		if k >= 60 {
+			values = withZero
+		}
+		codes = append(codes, code{
+			name:   fmt.Sprintf("rice parameter %d", k),
+			write:  func(bw *Writer, v uint64) error { return bw.WriteRice(v, k) },
+			read:   func(br *Reader) (uint64, error) { return br.ReadRice(k) },
+			values: values,
+		})
+	}
+	return codes
+}
+
+func TestCodesRoundTrip(t *testing.T) {
+	for _, c := range universalCodes() {
+		for _, order := range []BitOrder{MSBFirst, LSBFirst} {
+			t.Run(c.name+" "+order.String(), func(t *testing.T) {
+				for offset := range 8 {
+					buf := &bytes.Buffer{}
+					bw := NewWriterOrder(buf, order)
+					assert.NoError(t, bw.WriteBits(0, offset))
+					for _, v := range c.values {
+						assert.NoError(t, c.write(bw, v))
+					}
+					written := bw.BitsWritten()
+					assert.NoError(t, bw.Flush())
+
+					br := NewReaderOrder(bytes.NewReader(buf.Bytes()), order)
+					assert.NoError(t, br.Skip(int64(offset)))
+					for _, v := range c.values {
+						read, err := c.read(br)
+						assert.NoError(t, err)
+						Equal(t, v, read, "offset %d", offset)
+					}
+					Equal(t, written, br.BitsRead())
+				}
+			})
+		}
+	}
+}
+
+func TestCodesEOF(t *testing.T) {
+	for _, c := range universalCodes() {
+		t.Run(c.name, func(t *testing.T) {
+			v := c.values[len(c.values)-1]
+			buf := &bytes.Buffer{}
+			bw := NewWriter(buf)
+			assert.NoError(t, c.write(bw, v))
+			written := bw.BitsWritten()
+			assert.NoError(t, bw.Flush())
+
+			_, err := c.read(NewReader(bytes.NewReader(nil)))
+			Equal(t, io.EOF, err)
+
+			if written > 8 {
+				truncated := buf.Bytes()[:(written-1)/8]
+				_, err = c.read(NewReader(bytes.NewReader(truncated)))
+				Equal(t, io.ErrUnexpectedEOF, err)
+			}
+		})
+	}
+}
+
+func TestCodesErrors(t *testing.T) {
+	bw := NewWriter(io.Discard)
+	assert.ErrorContains(t, bw.WriteGamma(0), "cannot represent 0")
+	assert.ErrorContains(t, bw.WriteDelta(0), "cannot represent 0")
+	assert.ErrorContains(t, bw.WriteExpGolomb(math.MaxUint64, 0), "too large")
+	assert.ErrorContains(t, bw.WriteExpGolomb(1, 65), "out of range")
+	assert.ErrorContains(t, bw.WriteRice(1, -1), "out of range")
+
+	overflows := map[string]struct {
+		input []byte
+		read  func(br *Reader) (uint64, error)
+	}{
+		"gamma":    {input: make([]byte, 9), read: (*Reader).ReadGamma},
+		"delta":    {input: []byte{0b0000_0010, 0b0000_1000}, read: (*Reader).ReadDelta},
+		"rice":     {input: []byte{0b0000_0000, 0b0000_0000, 0b1000_0000}, read: func(br *Reader) (uint64, error) { return br.ReadRice(60) }},
+		"uvarint":  {input: bytes.Repeat([]byte{0xff}, 11), read: (*Reader).ReadUvarint},
+		"uvarint9": {input: append(bytes.Repeat([]byte{0xff}, 9), 0x02), read: (*Reader).ReadUvarint},
+	}
+	for name, o := range overflows {
+		input := append(o.input, bytes.Repeat([]byte{0xff}, 16)...)
+		_, err := o.read(NewReader(bytes.NewReader(input)))
+		assert.ErrorIs(t, err, errOverflow, name)
+	}
+
+	br := NewReader(bytes.NewReader([]byte{0x80}))
+	_, err := br.ReadRice(-1)
+	assert.ErrorContains(t, err, "out of range")
+	_, err = br.ReadExpGolomb(65)
+	assert.ErrorContains(t, err, "out of range")
+}
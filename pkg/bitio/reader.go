@@ -0,0 +1,187 @@
+package bitio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// MaxReadBits is the most bits a Reader can read or peek at once, what is left
+// of its 64 bit buffer when it can't take another whole byte.
+const MaxReadBits = 57
+
+// Reader reads bits from an io.Reader, in MSBFirst order unless made with
+// NewReaderOrder. It reads ahead of what has been returned, up to 8 bytes, so
+// the underlying reader is left at an unknown position.
+type Reader struct {
+	r     io.ByteReader
+	order BitOrder
+	// acc holds the n bits read ahead, at its top in MSBFirst order and at
+	// its bottom in LSBFirst
+	acc  uint64
+	n    int
+	read int64
+	err  error
+}
+
+// NewReader returns a Reader reading from r in MSBFirst order, through a
+// bufio.Reader unless r is an io.ByteReader already.
+func NewReader(r io.Reader) *Reader {
+	return NewReaderOrder(r, MSBFirst)
+}
+
+// NewReaderOrder returns a Reader that unpacks bits in the given order.
+func NewReaderOrder(r io.Reader, order BitOrder) *Reader {
+	byteReader, ok := r.(io.ByteReader)
+	if !ok {
+		byteReader = bufio.NewReader(r)
+	}
+	return &Reader{r: byteReader, order: order}
+}
+
+// fill reads ahead until at least n bits are buffered, or the underlying
+// reader fails.
+func (br *Reader) fill(n int) {
+	for br.n < n && br.err == nil {
+		b, err := br.r.ReadByte()
+		if err != nil {
+			br.err = err
+			return
+		}
+		if br.order == LSBFirst {
+			br.acc |= uint64(b) << br.n
+		} else {
+			br.acc |= uint64(b) << (56 - br.n)
+		}
+		br.n += 8
+	}
+}
+
+// Peek returns the next n bits, for n up to MaxReadBits, without consuming
+// them. In MSBFirst order the first of them is the most significant bit of the
+// value, in LSBFirst the least. It returns io.EOF when there are no bits left
+// at all, and io.ErrUnexpectedEOF when there are fewer than n.
+func (br *Reader) Peek(n int) (uint64, error) {
+	if n < 0 || n > MaxReadBits {
+		return 0, fmt.Errorf("error: cannot read %d bits at a time from a bitio.Reader, at most %d", n, MaxReadBits)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if br.n < n {
+		br.fill(MaxReadBits)
+	}
+	if br.n < n {
+		switch {
+		case br.err != io.EOF:
+			return 0, br.err
+		case br.n == 0:
+			return 0, io.EOF
+		}
+		return 0, io.ErrUnexpectedEOF
+	}
+	if br.order == LSBFirst {
+		return br.acc & (1<<n - 1), nil
+	}
+	return br.acc >> (64 - n), nil
+}
+
+// ReadBits reads the next n bits, for n up to MaxReadBits, as Peek does.
+func (br *Reader) ReadBits(n int) (uint64, error) {
+	value, err := br.Peek(n)
+	if err != nil {
+		return 0, err
+	}
+	br.consume(n)
+	return value, nil
+}
+
+// ReadBit reads the next bit.
+func (br *Reader) ReadBit() (byte, error) {
+	bit, err := br.ReadBits(1)
+	return byte(bit), err
+}
+
+// Read reads up to len(p) bytes as 8 bit values, the counterpart of
+// Writer.Write. On a byte boundary, once the bytes read ahead are used up, it
+// reads straight from the underlying reader.
+func (br *Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) && (br.n > 0 || br.read%8 != 0) {
+		b, err := br.ReadBits(8)
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		p[n] = byte(b)
+		n++
+	}
+	if n == len(p) {
+		return n, nil
+	}
+	if br.err != nil {
+		if n > 0 {
+			return n, nil
+		}
+		return 0, br.err
+	}
+
+	direct := n
+	var err error
+	if r, ok := br.r.(io.Reader); ok {
+		var m int
+		m, err = r.Read(p[n:])
+		n += m
+	} else {
+		for n < len(p) && err == nil {
+			p[n], err = br.r.ReadByte()
+			if err == nil {
+				n++
+			}
+		}
+	}
+	br.read += int64(n-direct) * 8
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Skip consumes the next n bits.
+func (br *Reader) Skip(n int64) error {
+	for n > 0 {
+		step := int(min(n, MaxReadBits))
+		if _, err := br.ReadBits(step); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		n -= int64(step)
+	}
+	return nil
+}
+
+// AlignToByte skips to the next byte boundary of the input, unless it is on
+// one already.
+func (br *Reader) AlignToByte() error {
+	return br.Skip((8 - br.read%8) % 8)
+}
+
+// BitsRead returns the number of bits consumed so far, the reader's position
+// in the input.
+func (br *Reader) BitsRead() int64 {
+	return br.read
+}
+
+func (br *Reader) consume(n int) {
+	if br.order == LSBFirst {
+		br.acc >>= n
+	} else {
+		br.acc <<= n
+	}
+	br.n -= n
+	br.read += int64(n)
+}
@@ -0,0 +1,194 @@
+package bitio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		order    BitOrder
+		input    []byte
+		widths   []int
+		expected []uint64
+	}{
+		{
+			name:     "within a byte",
+			input:    []byte{0b1011_1000},
+			widths:   []int{3, 2, 3},
+			expected: []uint64{0b101, 0b11, 0},
+		},
+		{
+			name:     "straddling bytes",
+			input:    []byte{0b1011_1111, 0b0000_0000},
+			widths:   []int{3, 9, 4},
+			expected: []uint64{0b101, 0b1_1111_0000, 0},
+		},
+		{
+			name:     "57 bits",
+			input:    []byte{0x10, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde},
+			widths:   []int{4, 57},
+			expected: []uint64{1, 0x0123_4567_89ab_cdef >> 7},
+		},
+		{
+			name:     "nothing",
+			input:    []byte{0xff},
+			widths:   []int{0, 8, 0},
+			expected: []uint64{0, 0xff, 0},
+		},
+		{
+			name:     "lsb within a byte",
+			order:    LSBFirst,
+			input:    []byte{0b0001_1101},
+			widths:   []int{3, 2, 3},
+			expected: []uint64{0b101, 0b11, 0},
+		},
+		{
+			name:     "lsb straddling bytes",
+			order:    LSBFirst,
+			input:    []byte{0b1111_1101, 0b0000_0011},
+			widths:   []int{1, 2, 7, 6},
+			expected: []uint64{1, 0b10, 0b111_1111, 0},
+		},
+		{
+			name:     "lsb 57 bits",
+			order:    LSBFirst,
+			input:    []byte{0xf1, 0xde, 0xbc, 0x9a, 0x78, 0x56, 0x34, 0x12},
+			widths:   []int{4, 57},
+			expected: []uint64{1, 0x0123_4567_89ab_cdef & (1<<57 - 1)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			br := NewReaderOrder(bytes.NewReader(tc.input), tc.order)
+			var position int64
+			for i, width := range tc.widths {
+				peeked, err := br.Peek(width)
+				assert.NoError(t, err)
+				Equal(t, tc.expected[i], peeked, "peek %d", i)
+				read, err := br.ReadBits(width)
+				assert.NoError(t, err)
+				Equal(t, tc.expected[i], read, "read %d", i)
+				position += int64(width)
+				Equal(t, position, br.BitsRead())
+			}
+		})
+	}
+}
+
+func TestReaderUnderlyingReaders(t *testing.T) {
+	// writes values of every width from 1 to MaxReadBits
+	buf := &bytes.Buffer{}
+	bw := NewWriter(buf)
+	for n := 1; n <= MaxReadBits; n++ {
+		assert.NoError(t, bw.WriteBits(uint64(n)*0x9e37_79b9_7f4a_7c15, n))
+	}
+	assert.NoError(t, bw.Flush())
+
+	readers := map[string]io.Reader{
+		"byte reader":     bytes.NewReader(buf.Bytes()),
+		"plain reader":    iotest.OneByteReader(bytes.NewReader(buf.Bytes())),
+		"erroring reader": iotest.DataErrReader(bytes.NewReader(buf.Bytes())),
+	}
+	for name, r := range readers {
+		t.Run(name, func(t *testing.T) {
+			br := NewReader(r)
+			for n := 1; n <= MaxReadBits; n++ {
+				read, err := br.ReadBits(n)
+				assert.NoError(t, err)
+				Equal(t, uint64(n)*0x9e37_79b9_7f4a_7c15&(1<<n-1), read, "reading %d bits", n)
+			}
+			assert.NoError(t, br.AlignToByte())
+			Equal(t, bw.BitsWritten(), br.BitsRead())
+			_, err := br.ReadBits(1)
+			Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestReaderRead(t *testing.T) {
+	testCases := []struct {
+		name     string
+		order    BitOrder
+		input    []byte
+		skip     int64
+		expected []byte
+	}{
+		{name: "aligned", input: []byte{0xab, 0xcd}, expected: []byte{0xab, 0xcd}},
+		{name: "after read ahead bytes", input: []byte{0x01, 0xab, 0xcd}, skip: 8, expected: []byte{0xab, 0xcd}},
+		{name: "unaligned", input: []byte{0xd5, 0xe6, 0x80}, skip: 1, expected: []byte{0xab, 0xcd}},
+		{name: "lsb unaligned", order: LSBFirst, input: []byte{0x57, 0x9b, 0x01}, skip: 1, expected: []byte{0xab, 0xcd}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			br := NewReaderOrder(bytes.NewReader(tc.input), tc.order)
+			assert.NoError(t, br.Skip(tc.skip))
+			p := make([]byte, len(tc.expected))
+			_, err := io.ReadFull(br, p)
+			assert.NoError(t, err)
+			Equal(t, tc.expected, p)
+			Equal(t, tc.skip+int64(len(p))*8, br.BitsRead())
+		})
+	}
+
+	t.Run("eof", func(t *testing.T) {
+		br := NewReader(bytes.NewReader([]byte{0xab}))
+		assert.NoError(t, br.Skip(4))
+		p := make([]byte, 2)
+		_, err := br.Read(p)
+		Equal(t, io.ErrUnexpectedEOF, err)
+
+		br = NewReader(bytes.NewReader([]byte{0xab}))
+		n, err := br.Read(p)
+		assert.NoError(t, err)
+		Equal(t, 1, n)
+		_, err = br.Read(p)
+		Equal(t, io.EOF, err)
+	})
+}
+
+func TestReaderSkipAndAlign(t *testing.T) {
+	input := bytes.Repeat([]byte{0x0f, 0xf0}, 20)
+	br := NewReader(bytes.NewReader(input))
+
+	assert.NoError(t, br.Skip(3))
+	assert.NoError(t, br.AlignToByte())
+	Equal(t, int64(8), br.BitsRead())
+	assert.NoError(t, br.AlignToByte())
+	Equal(t, int64(8), br.BitsRead())
+
+	assert.NoError(t, br.Skip(8*29+4))
+	bits, err := br.ReadBits(8)
+	assert.NoError(t, err)
+	Equal(t, uint64(0xff), bits)
+
+	Equal(t, io.ErrUnexpectedEOF, br.Skip(100))
+}
+
+func TestReaderErrors(t *testing.T) {
+	br := NewReader(bytes.NewReader([]byte{0xab}))
+	_, err := br.ReadBits(MaxReadBits + 1)
+	assert.Error(t, err)
+	_, err = br.Peek(-1)
+	assert.Error(t, err)
+
+	_, err = br.ReadBits(9)
+	Equal(t, io.ErrUnexpectedEOF, err)
+	// a failed read consumes nothing
+	bits, err := br.ReadBits(8)
+	assert.NoError(t, err)
+	Equal(t, uint64(0xab), bits)
+	_, err = br.ReadBit()
+	Equal(t, io.EOF, err)
+
+	br = NewReader(iotest.TimeoutReader(bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})))
+	_, err = br.ReadBits(MaxReadBits)
+	assert.NoError(t, err)
+	_, err = br.ReadBits(MaxReadBits)
+	assert.ErrorIs(t, err, iotest.ErrTimeout)
+}
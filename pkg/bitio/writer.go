@@ -0,0 +1,164 @@
+package bitio
+
+import (
+	"fmt"
+	"io"
+)
+
+// writerBufferSize is how many whole bytes a Writer collects before writing
+// them out.
+const writerBufferSize = 4096
+
+// Writer writes bits to an io.Writer, in MSBFirst order unless made with
+// NewWriterOrder. Whole bytes are buffered and written out as the buffer fills,
+// Flush writes out the rest.
+//
+// The first error writing to the underlying writer is kept, and returned by
+// every later call.
+type Writer struct {
+	w     io.Writer
+	order BitOrder
+	// acc holds the last n bits written that do not yet make up a whole
+	// byte, at its bottom
+	acc     uint64
+	n       int
+	buf     []byte
+	written int64
+	err     error
+}
+
+// NewWriter returns a Writer writing to w in MSBFirst order.
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterOrder(w, MSBFirst)
+}
+
+// NewWriterOrder returns a Writer that packs bits in the given order.
+func NewWriterOrder(w io.Writer, order BitOrder) *Writer {
+	return &Writer{w: w, order: order, buf: make([]byte, 0, writerBufferSize)}
+}
+
+// WriteBits writes the n low bits of value, for n up to 64. In MSBFirst order
+// the most significant of them is written first, in LSBFirst the least.
+func (bw *Writer) WriteBits(value uint64, n int) error {
+	if n < 0 || n > 64 {
+		return fmt.Errorf("error: cannot write %d bits at a time to a bitio.Writer, at most 64", n)
+	}
+	if bw.err != nil {
+		return bw.err
+	}
+	if n > 56 {
+		// acc can't take more than 56 bits on top of the 7 it may hold
+		high, low := value>>32, value&(1<<32-1)
+		if bw.order == LSBFirst {
+			if err := bw.WriteBits(low, 32); err != nil {
+				return err
+			}
+			value, n = high, n-32
+		} else {
+			if err := bw.WriteBits(high, n-32); err != nil {
+				return err
+			}
+			value, n = low, 32
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+
+	value &= 1<<n - 1
+	if bw.order == LSBFirst {
+		bw.acc |= value << bw.n
+	} else {
+		bw.acc = bw.acc<<n | value
+	}
+	bw.n += n
+	for bw.n >= 8 {
+		bw.n -= 8
+		if bw.order == LSBFirst {
+			bw.buf = append(bw.buf, byte(bw.acc))
+			bw.acc >>= 8
+		} else {
+			bw.buf = append(bw.buf, byte(bw.acc>>bw.n))
+		}
+		if len(bw.buf) == cap(bw.buf) {
+			if err := bw.writeBuffer(); err != nil {
+				return err
+			}
+		}
+	}
+	bw.acc &= 1<<bw.n - 1
+	return nil
+}
+
+// WriteBit writes a single bit, 0 or 1.
+func (bw *Writer) WriteBit(bit byte) error {
+	return bw.WriteBits(uint64(bit), 1)
+}
+
+// Write writes the bytes of p as 8 bit values. On a byte boundary they are
+// copied as they are, in either order.
+func (bw *Writer) Write(p []byte) (int, error) {
+	if bw.err != nil {
+		return 0, bw.err
+	}
+	if bw.n != 0 {
+		for i, b := range p {
+			if err := bw.WriteBits(uint64(b), 8); err != nil {
+				return i, err
+			}
+		}
+		return len(p), nil
+	}
+
+	written := 0
+	for written < len(p) {
+		copied := copy(bw.buf[len(bw.buf):cap(bw.buf)], p[written:])
+		bw.buf = bw.buf[:len(bw.buf)+copied]
+		written += copied
+		if len(bw.buf) == cap(bw.buf) {
+			if err := bw.writeBuffer(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Align pads the last byte with zeroes, up to a byte boundary.
+func (bw *Writer) Align() error {
+	if bw.n == 0 {
+		return bw.err
+	}
+	return bw.WriteBits(0, 8-bw.n)
+}
+
+// Flush aligns to a byte boundary and writes every buffered byte out.
+func (bw *Writer) Flush() error {
+	if err := bw.Align(); err != nil {
+		return err
+	}
+	return bw.writeBuffer()
+}
+
+// BitsWritten returns the number of bits written so far, whether or not they
+// have been flushed.
+func (bw *Writer) BitsWritten() int64 {
+	return (bw.written+int64(len(bw.buf)))*8 + int64(bw.n)
+}
+
+func (bw *Writer) writeBuffer() error {
+	if bw.err != nil {
+		return bw.err
+	}
+	n, err := bw.w.Write(bw.buf)
+	bw.written += int64(n)
+	if err == nil && n < len(bw.buf) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		bw.err = err
+		return err
+	}
+	bw.buf = bw.buf[:0]
+	return nil
+}
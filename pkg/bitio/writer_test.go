@@ -0,0 +1,166 @@
+package bitio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bits struct {
+	value uint64
+	n     int
+}
+
+func TestWriteBits(t *testing.T) {
+	testCases := []struct {
+		name     string
+		order    BitOrder
+		writes   []bits
+		expected []byte
+	}{
+		{
+			name:     "within a byte",
+			writes:   []bits{{0b101, 3}, {0b11, 2}},
+			expected: []byte{0b1011_1000},
+		},
+		{
+			name:     "straddling bytes",
+			writes:   []bits{{0b101, 3}, {0b1_1111_0000, 9}},
+			expected: []byte{0b1011_1111, 0b0000_0000},
+		},
+		{
+			name:     "high bits are ignored",
+			writes:   []bits{{0xff, 1}, {0xf0, 7}},
+			expected: []byte{0b1111_0000},
+		},
+		{
+			name:     "64 bits",
+			writes:   []bits{{1, 4}, {0x0123_4567_89ab_cdef, 64}},
+			expected: []byte{0x10, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0},
+		},
+		{
+			name:     "nothing",
+			writes:   []bits{{0xff, 0}},
+			expected: nil,
+		},
+		{
+			name:     "lsb within a byte",
+			order:    LSBFirst,
+			writes:   []bits{{0b101, 3}, {0b11, 2}},
+			expected: []byte{0b0001_1101},
+		},
+		{
+			name:     "lsb straddling bytes",
+			order:    LSBFirst,
+			writes:   []bits{{1, 1}, {0b10, 2}, {0b111_1111, 7}},
+			expected: []byte{0b1111_1101, 0b0000_0011},
+		},
+		{
+			name:     "lsb 64 bits",
+			order:    LSBFirst,
+			writes:   []bits{{1, 4}, {0x0123_4567_89ab_cdef, 64}},
+			expected: []byte{0xf1, 0xde, 0xbc, 0x9a, 0x78, 0x56, 0x34, 0x12, 0x00},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			bw := NewWriterOrder(buf, tc.order)
+			var written int64
+			for _, w := range tc.writes {
+				assert.NoError(t, bw.WriteBits(w.value, w.n))
+				written += int64(w.n)
+			}
+			Equal(t, written, bw.BitsWritten())
+			assert.NoError(t, bw.Flush())
+			Equal(t, tc.expected, buf.Bytes())
+			Equal(t, int64(len(tc.expected))*8, bw.BitsWritten())
+		})
+	}
+}
+
+func TestWriterWrite(t *testing.T) {
+	testCases := []struct {
+		name     string
+		order    BitOrder
+		before   bits
+		input    []byte
+		expected []byte
+	}{
+		{name: "aligned", input: []byte{0xab, 0xcd}, expected: []byte{0xab, 0xcd}},
+		{name: "lsb aligned", order: LSBFirst, input: []byte{0xab, 0xcd}, expected: []byte{0xab, 0xcd}},
+		{name: "unaligned", before: bits{1, 1}, input: []byte{0xab, 0xcd}, expected: []byte{0xd5, 0xe6, 0x80}},
+		{name: "lsb unaligned", order: LSBFirst, before: bits{1, 1}, input: []byte{0xab, 0xcd}, expected: []byte{0x57, 0x9b, 0x01}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			bw := NewWriterOrder(buf, tc.order)
+			assert.NoError(t, bw.WriteBits(tc.before.value, tc.before.n))
+			n, err := bw.Write(tc.input)
+			assert.NoError(t, err)
+			Equal(t, len(tc.input), n)
+			assert.NoError(t, bw.Flush())
+			Equal(t, tc.expected, buf.Bytes())
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("align", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		bw := NewWriter(buf)
+		assert.NoError(t, bw.Align())
+		assert.NoError(t, bw.WriteBit(1))
+		assert.NoError(t, bw.Align())
+		Equal(t, int64(8), bw.BitsWritten())
+		assert.NoError(t, bw.WriteBits(0xff, 8))
+		assert.NoError(t, bw.Flush())
+		Equal(t, []byte{0x80, 0xff}, buf.Bytes())
+	})
+
+	t.Run("bit counts out of range", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		bw := NewWriter(buf)
+		assert.Error(t, bw.WriteBits(0, -1))
+		assert.Error(t, bw.WriteBits(0, 65))
+		Equal(t, int64(0), bw.BitsWritten())
+		assert.NoError(t, bw.WriteBits(1, 1))
+		assert.NoError(t, bw.Flush())
+		Equal(t, []byte{0x80}, buf.Bytes())
+	})
+
+	t.Run("writes out full buffers", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		bw := NewWriter(buf)
+		for range writerBufferSize + 1 {
+			assert.NoError(t, bw.WriteBits(0xaa, 8))
+		}
+		Equal(t, writerBufferSize, buf.Len())
+		assert.NoError(t, bw.Flush())
+		Equal(t, writerBufferSize+1, buf.Len())
+	})
+
+	t.Run("errors propagate", func(t *testing.T) {
+		errBroken := errors.New("broken")
+		bw := NewWriter(writerFunc(func(p []byte) (int, error) {
+			return 0, errBroken
+		}))
+		assert.NoError(t, bw.WriteBits(1, 1))
+		assert.ErrorIs(t, bw.Flush(), errBroken)
+		assert.ErrorIs(t, bw.WriteBits(1, 1), errBroken)
+		_, err := bw.Write([]byte{1})
+		assert.ErrorIs(t, err, errBroken)
+	})
+
+	t.Run("short writes", func(t *testing.T) {
+		bw := NewWriter(writerFunc(func(p []byte) (int, error) {
+			return len(p) - 1, nil
+		}))
+		assert.NoError(t, bw.WriteBits(0xffff, 16))
+		assert.ErrorIs(t, bw.Flush(), io.ErrShortWrite)
+	})
+}
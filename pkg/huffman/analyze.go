@@ -0,0 +1,82 @@
+package huffman
+
+import (
+	"math"
+	"slices"
+)
+
+// Analysis describes how well input compresses, as reported by Analyze.
+type Analysis struct {
+	// Length is the length of the input in bytes.
+	Length int
+	// Entropy is the Shannon entropy of the input in bits per symbol, the
+	// least any code that encodes symbols one at a time can average.
+	Entropy float64
+	// AverageCodeLength is the number of bits the Huffman code spends per
+	// symbol on average.
+	AverageCodeLength float64
+	// Efficiency is Entropy divided by AverageCodeLength, 1 being a perfect
+	// code.
+	Efficiency float64
+	// PredictedSize is the length of Encode's output for the input in
+	// bytes, header and tree included.
+	PredictedSize int
+	TreeDepth     int
+	Leaves        int
+	// Symbols holds the statistics of every symbol in the input, ordered by
+	// symbol.
+	Symbols []SymbolStats
+}
+
+// SymbolStats describes one symbol of an analysed input.
+type SymbolStats struct {
+	Symbol      byte
+	Freq        int
+	Probability float64
+	CodeLength  int
+}
+
+// Analyze reports how input would compress without encoding it.
+func Analyze(input []byte) *Analysis {
+	// the header is written whatever the input
+	a := &Analysis{Length: len(input), PredictedSize: 4, Efficiency: 1}
+	if len(input) == 0 {
+		return a
+	}
+
+	freqs := computeFreqTable(input)
+	tree := NewNode(freqs)
+	codes := tree.Codes()
+
+	var contentBits int
+	for _, f := range freqs {
+		p := float64(f.freq) / float64(len(input))
+		codeLength := len(codes[f.char])
+		a.Symbols = append(a.Symbols, SymbolStats{
+			Symbol:      f.char,
+			Freq:        f.freq,
+			Probability: p,
+			CodeLength:  codeLength,
+		})
+		a.Entropy -= p * math.Log2(p)
+		a.AverageCodeLength += p * float64(codeLength)
+		a.TreeDepth = max(a.TreeDepth, codeLength)
+		contentBits += f.freq * codeLength
+	}
+	slices.SortFunc(a.Symbols, func(x, y SymbolStats) int {
+		return int(x.Symbol) - int(y.Symbol)
+	})
+	a.Leaves = len(a.Symbols)
+
+	// a single symbol needs no bits at all, which is as good as it gets
+	if a.AverageCodeLength > 0 {
+		a.Efficiency = a.Entropy / a.AverageCodeLength
+	}
+
+	// Encode writes the tree in the smaller compact form, and when that is a
+	// bitmap tree encodes the content with the canonical tree instead, whose
+	// codes have the same lengths as these
+	a.PredictedSize += (compactTreeBits(a.Leaves) + contentBits + 7) / 8
+
+	return a
+}
@@ -0,0 +1,59 @@
+package huffman
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	t.Run("predicts the encoded size", func(t *testing.T) {
+		inputs := []string{
+			"",
+			"aaaa",
+			"ab",
+			"hello world",
+			strings.Repeat("the quick brown fox jumps over the lazy dog ", 100),
+		}
+		for _, input := range inputs {
+			encoded, err := Encode([]byte(input))
+			assert.NoError(t, err)
+			Equal(t, len(encoded), Analyze([]byte(input)).PredictedSize)
+		}
+	})
+
+	t.Run("statistics", func(t *testing.T) {
+		// probabilities of 1/2, 1/4 and 1/4 have a perfect code
+		a := Analyze([]byte("aaaabbcc"))
+		Equal(t, 8, a.Length)
+		Equal(t, 3, a.Leaves)
+		Equal(t, 2, a.TreeDepth)
+		Equal(t, 1.5, a.Entropy)
+		Equal(t, 1.5, a.AverageCodeLength)
+		Equal(t, 1.0, a.Efficiency)
+		Equal(t, []SymbolStats{
+			{Symbol: 'a', Freq: 4, Probability: 0.5, CodeLength: 1},
+			{Symbol: 'b', Freq: 2, Probability: 0.25, CodeLength: 2},
+			{Symbol: 'c', Freq: 2, Probability: 0.25, CodeLength: 2},
+		}, a.Symbols)
+	})
+
+	t.Run("efficiency below one", func(t *testing.T) {
+		a := Analyze([]byte("aab"))
+		entropy := -(2.0/3)*math.Log2(2.0/3) - (1.0/3)*math.Log2(1.0/3)
+		assert.InDelta(t, entropy, a.Entropy, 1e-9)
+		Equal(t, 1.0, a.AverageCodeLength)
+		assert.Less(t, a.Efficiency, 1.0)
+	})
+
+	t.Run("single symbol", func(t *testing.T) {
+		a := Analyze([]byte("zzz"))
+		Equal(t, 0.0, a.Entropy)
+		Equal(t, 0.0, a.AverageCodeLength)
+		Equal(t, 1.0, a.Efficiency)
+		Equal(t, 1, a.Leaves)
+		Equal(t, 0, a.TreeDepth)
+	})
+}
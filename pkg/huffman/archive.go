@@ -0,0 +1,280 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// An archive stores many files in one container. Every regular file is stored
+// as its own block stream, and a central directory at the end of the archive
+// describes every entry and where its block stream lives.
+//
+// layout:
+//
+//	archive                 = header { blockStream } directory trailer .
+//	header        (4 bytes) = archiveMagic .
+//	directory               = { directoryEntry } .
+//	directoryEntry          = pathLength (2 bytes) path (pathLength bytes) mode (4 bytes)
+//	                          modTime (8 bytes) size (8 bytes) offset (8 bytes) length (8 bytes) .
+//	trailer      (16 bytes) = directoryOffset (8 bytes) count (4 bytes) directoryMagic .
+//
+// All integers are big endian. path is slash separated and relative, mode is
+// an fs.FileMode, modTime is in nanoseconds since the unix epoch and size is
+// the length of the file. offset and length locate the file's block stream,
+// directories have none.
+const (
+	archiveHeaderLen  = 4
+	archiveTrailerLen = 16
+	maxArchivePathLen = 1<<16 - 1
+)
+
+var (
+	archiveMagic   = [4]byte{0x89, 'H', 'F', 'A'}
+	directoryMagic = [4]byte{0x89, 'H', 'F', 'D'}
+)
+
+// ArchiveEntry describes a file or directory stored in an archive.
+type ArchiveEntry struct {
+	// Path is slash separated and relative to the root of the archive, as
+	// accepted by fs.ValidPath.
+	Path    string
+	Mode    fs.FileMode
+	ModTime time.Time
+	Size    int64
+
+	offset int64
+	length int64
+}
+
+// ArchiveWriter writes an archive. Close must be called to write the central
+// directory, it does not close the underlying writer.
+type ArchiveWriter struct {
+	w       io.Writer
+	written int64
+	entries []ArchiveEntry
+	err     error
+}
+
+func NewArchiveWriter(w io.Writer) *ArchiveWriter {
+	return &ArchiveWriter{w: w}
+}
+
+// Add adds an entry to the archive. For regular files the contents are read
+// from r until io.EOF and must be e.Size bytes long, for directories r is
+// ignored and may be nil.
+func (aw *ArchiveWriter) Add(e ArchiveEntry, r io.Reader) error {
+	if aw.err != nil {
+		return aw.err
+	}
+	if !fs.ValidPath(e.Path) || e.Path == "." || len(e.Path) > maxArchivePathLen {
+		return fmt.Errorf("error: %q is not a valid path within an archive", e.Path)
+	}
+	if !e.Mode.IsRegular() && !e.Mode.IsDir() {
+		return fmt.Errorf("error: %q is neither a regular file nor a directory", e.Path)
+	}
+
+	if err := aw.writeHeader(); err != nil {
+		return err
+	}
+
+	e.offset, e.length = 0, 0
+	if e.Mode.IsRegular() {
+		e.offset = aw.written
+		bw := NewBlockWriter(writerFunc(aw.write), DefaultBlockSize)
+		n, err := io.Copy(bw, r)
+		if err != nil {
+			return err
+		}
+		if err := bw.Close(); err != nil {
+			return err
+		}
+		if n != e.Size {
+			aw.err = fmt.Errorf("error: %q was %d bytes long but its entry expected %d", e.Path, n, e.Size)
+			return aw.err
+		}
+		e.length = aw.written - e.offset
+	} else {
+		e.Size = 0
+	}
+
+	aw.entries = append(aw.entries, e)
+	return nil
+}
+
+func (aw *ArchiveWriter) Close() error {
+	if aw.err != nil {
+		return aw.err
+	}
+	if err := aw.writeHeader(); err != nil {
+		return err
+	}
+
+	directoryOffset := aw.written
+	var out []byte
+	for _, e := range aw.entries {
+		out = binary.BigEndian.AppendUint16(out, uint16(len(e.Path)))
+		out = append(out, e.Path...)
+		out = binary.BigEndian.AppendUint32(out, uint32(e.Mode))
+		out = binary.BigEndian.AppendUint64(out, uint64(e.ModTime.UnixNano()))
+		out = binary.BigEndian.AppendUint64(out, uint64(e.Size))
+		out = binary.BigEndian.AppendUint64(out, uint64(e.offset))
+		out = binary.BigEndian.AppendUint64(out, uint64(e.length))
+	}
+	out = binary.BigEndian.AppendUint64(out, uint64(directoryOffset))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(aw.entries)))
+	out = append(out, directoryMagic[:]...)
+	if _, err := aw.write(out); err != nil {
+		return err
+	}
+
+	aw.err = fmt.Errorf("error: write to a closed ArchiveWriter")
+	return nil
+}
+
+// write writes p to the underlying writer, keeping track of the archive's
+// length.
+func (aw *ArchiveWriter) write(p []byte) (int, error) {
+	if aw.err != nil {
+		return 0, aw.err
+	}
+	n, err := aw.w.Write(p)
+	aw.written += int64(n)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		aw.err = err
+	}
+	return n, err
+}
+
+func (aw *ArchiveWriter) writeHeader() error {
+	if aw.written > 0 {
+		return nil
+	}
+	_, err := aw.write(archiveMagic[:])
+	return err
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+// IsArchive reports whether input starts like an archive.
+func IsArchive(input []byte) bool {
+	return len(input) >= archiveHeaderLen && [4]byte(input[:archiveHeaderLen]) == archiveMagic
+}
+
+// ArchiveReader reads the entries of an archive.
+type ArchiveReader struct {
+	r       io.ReaderAt
+	Entries []ArchiveEntry
+}
+
+// OpenArchive reads the central directory of the archive held in r, which is
+// size bytes long. Entries whose paths are not valid relative paths are
+// rejected as corrupt.
+func OpenArchive(r io.ReaderAt, size int64) (*ArchiveReader, error) {
+	if size < archiveHeaderLen+archiveTrailerLen {
+		return nil, corruptInput(0, "error: an archive of %d bytes is too short", size)
+	}
+
+	header := make([]byte, archiveHeaderLen)
+	if err := readFullAt(r, header, 0); err != nil {
+		return nil, err
+	}
+	if !IsArchive(header) {
+		return nil, corruptInput(0, "error: input is not an archive, header was %x", header)
+	}
+
+	trailer := make([]byte, archiveTrailerLen)
+	if err := readFullAt(r, trailer, size-archiveTrailerLen); err != nil {
+		return nil, err
+	}
+	if [4]byte(trailer[12:]) != directoryMagic {
+		return nil, corruptInput(size-archiveTrailerLen, "error: archive trailer ended with %x, expected %x", trailer[12:], directoryMagic)
+	}
+	directoryOffset := int64(binary.BigEndian.Uint64(trailer[0:8]))
+	count := int(binary.BigEndian.Uint32(trailer[8:12]))
+	if directoryOffset < archiveHeaderLen || directoryOffset > size-archiveTrailerLen {
+		return nil, corruptInput(size-archiveTrailerLen, "error: archive directory at %d does not fit an archive of %d bytes", directoryOffset, size)
+	}
+
+	raw := make([]byte, size-archiveTrailerLen-directoryOffset)
+	if err := readFullAt(r, raw, directoryOffset); err != nil {
+		return nil, err
+	}
+
+	ar := &ArchiveReader{r: r}
+	for i := range count {
+		entryOffset := size - archiveTrailerLen - int64(len(raw))
+		if len(raw) < 2 {
+			return nil, corruptInput(entryOffset, "error: archive directory ended before entry %d", i)
+		}
+		pathLength := int(binary.BigEndian.Uint16(raw))
+		if len(raw) < 2+pathLength+36 {
+			return nil, corruptInput(entryOffset, "error: archive directory ended before entry %d", i)
+		}
+		e := ArchiveEntry{Path: string(raw[2 : 2+pathLength])}
+		raw = raw[2+pathLength:]
+		e.Mode = fs.FileMode(binary.BigEndian.Uint32(raw[0:4]))
+		e.ModTime = time.Unix(0, int64(binary.BigEndian.Uint64(raw[4:12])))
+		e.Size = int64(binary.BigEndian.Uint64(raw[12:20]))
+		e.offset = int64(binary.BigEndian.Uint64(raw[20:28]))
+		e.length = int64(binary.BigEndian.Uint64(raw[28:36]))
+		raw = raw[36:]
+
+		if !fs.ValidPath(e.Path) || e.Path == "." {
+			return nil, corruptInput(entryOffset, "error: archive entry %d has the invalid path %q", i, e.Path)
+		}
+		if !e.Mode.IsRegular() && !e.Mode.IsDir() {
+			return nil, corruptInput(entryOffset, "error: archive entry %q is neither a regular file nor a directory", e.Path)
+		}
+		if e.Size < 0 || e.offset < 0 || e.length < 0 || e.offset+e.length > directoryOffset {
+			return nil, corruptInput(entryOffset, "error: archive entry %q lies outside of the archive", e.Path)
+		}
+		ar.Entries = append(ar.Entries, e)
+	}
+	if len(raw) > 0 {
+		return nil, corruptInput(size-archiveTrailerLen-int64(len(raw)), "error: archive directory has %d bytes after its last entry", len(raw))
+	}
+
+	return ar, nil
+}
+
+// Open returns a reader of the decoded contents of e, which must be one of
+// ar.Entries. Directories have no contents.
+func (ar *ArchiveReader) Open(e ArchiveEntry) io.Reader {
+	if !e.Mode.IsRegular() {
+		return &io.LimitedReader{}
+	}
+	return &archiveFileReader{
+		r:    NewBlockReader(io.NewSectionReader(ar.r, e.offset, e.length)),
+		e:    e,
+		left: e.Size,
+	}
+}
+
+// archiveFileReader checks that a file decodes to the size its entry records.
+type archiveFileReader struct {
+	r    io.Reader
+	e    ArchiveEntry
+	left int64
+}
+
+func (r *archiveFileReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.left -= int64(n)
+	if err != nil && err != io.EOF {
+		return n, shiftOffset(err, r.e.offset)
+	}
+	if r.left < 0 || (err == io.EOF && r.left > 0) {
+		return n, corruptInput(r.e.offset, "error: %q decoded to %d bytes but its entry expected %d", r.e.Path, r.e.Size-r.left, r.e.Size)
+	}
+	return n, err
+}
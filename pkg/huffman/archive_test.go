@@ -0,0 +1,101 @@
+package huffman
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	modTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	files := map[string]string{
+		"dir/hello.txt": "hello world",
+		"dir/empty.txt": "",
+		"big.txt":       strings.Repeat("lots and lots of text ", 5000),
+	}
+
+	buf := &bytes.Buffer{}
+	aw := NewArchiveWriter(buf)
+	assert.NoError(t, aw.Add(ArchiveEntry{Path: "dir", Mode: fs.ModeDir | 0755, ModTime: modTime}, nil))
+	for _, path := range []string{"dir/hello.txt", "dir/empty.txt", "big.txt"} {
+		contents := files[path]
+		e := ArchiveEntry{Path: path, Mode: 0644, ModTime: modTime, Size: int64(len(contents))}
+		assert.NoError(t, aw.Add(e, strings.NewReader(contents)))
+	}
+	assert.NoError(t, aw.Close())
+	Equal(t, true, IsArchive(buf.Bytes()))
+
+	ar, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	Equal(t, 4, len(ar.Entries))
+
+	Equal(t, "dir", ar.Entries[0].Path)
+	Equal(t, true, ar.Entries[0].Mode.IsDir())
+	for _, e := range ar.Entries[1:] {
+		Equal(t, fs.FileMode(0644), e.Mode)
+		Equal(t, true, modTime.Equal(e.ModTime))
+		Equal(t, int64(len(files[e.Path])), e.Size)
+
+		contents, err := io.ReadAll(ar.Open(e))
+		assert.NoError(t, err)
+		Equal(t, files[e.Path], string(contents))
+	}
+}
+
+func TestArchiveCorruptFile(t *testing.T) {
+	contents := strings.Repeat("lots and lots of text ", 500)
+	buf := &bytes.Buffer{}
+	aw := NewArchiveWriter(buf)
+	assert.NoError(t, aw.Add(ArchiveEntry{Path: "a.txt", Mode: 0644, Size: int64(len(contents))}, strings.NewReader(contents)))
+	assert.NoError(t, aw.Close())
+
+	ar, err := OpenArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+	e := ar.Entries[0]
+
+	// the block stream starts with its header and the length of its first block,
+	// which is followed by the version bits of that block's header
+	corrupted := bytes.Clone(buf.Bytes())
+	corrupted[e.offset+blockHeaderLen+4] |= 0b1100_0000
+	ar, err = OpenArchive(bytes.NewReader(corrupted), int64(len(corrupted)))
+	assert.NoError(t, err)
+
+	// the reader returns its error again on every call, which must not move
+	// the offset again
+	r := ar.Open(e)
+	p := make([]byte, 64)
+	for range 2 {
+		_, err := r.Read(p)
+		var corruptErr *CorruptInputError
+		assert.ErrorAs(t, err, &corruptErr)
+		Equal(t, e.offset+blockHeaderLen+4, corruptErr.Offset)
+	}
+}
+
+func TestArchiveRejectsInvalidPaths(t *testing.T) {
+	for _, path := range []string{"", ".", "/etc/passwd", "../escape", "a/../../b", "a//b", "a/"} {
+		t.Run(path, func(t *testing.T) {
+			aw := NewArchiveWriter(io.Discard)
+			err := aw.Add(ArchiveEntry{Path: path, Mode: 0644}, strings.NewReader(""))
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("within an archive", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		aw := NewArchiveWriter(buf)
+		assert.NoError(t, aw.Add(ArchiveEntry{Path: "aa/escape", Mode: 0644, Size: 1}, strings.NewReader("x")))
+		assert.NoError(t, aw.Close())
+
+		// rewrite the path in the directory as one that escapes the archive
+		archive := bytes.Replace(buf.Bytes(), []byte("aa/escape"), []byte("../escape"), 1)
+		_, err := OpenArchive(bytes.NewReader(archive), int64(len(archive)))
+		var corruptErr *CorruptInputError
+		assert.ErrorAs(t, err, &corruptErr)
+	})
+}
@@ -20,14 +20,18 @@ func (bs *BitStringReader) Read(w int) (byte, error) {
 		return 0, fmt.Errorf("error: cannot read more than 8 bits at a time from BitStringReader")
 	}
 
+	if bs.currentByte >= len(bs.buffer) {
+		return 0, fmt.Errorf("error: attempting to read byte %d from a buffer with len %d", bs.currentByte, len(bs.buffer))
+	}
+
 	var output byte
 	leftBitsRemaining := 8 - bs.offset
 	if w > leftBitsRemaining {
 		// compute left side
-		output = (bs.buffer[bs.currentByte] & onesMask(leftBitsRemaining)) << bs.offset
+		rightBits := w - leftBitsRemaining
+		output = (bs.buffer[bs.currentByte] & onesMask(leftBitsRemaining)) << rightBits
 
 		// compute right side
-		rightBits := w - leftBitsRemaining
 		rightMaskShift := 8 - rightBits
 		rightMask := onesMask(rightBits) << rightMaskShift
 		if (bs.currentByte + 1) >= len(bs.buffer) {
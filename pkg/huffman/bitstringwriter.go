@@ -71,7 +71,13 @@ func (bs *BitStringWriter) WriteBytes(bytes []byte, w int) {
 // Writes the trailing 30 bits (ignores the leading 2 bits) of contentLength
 // representing the uncompressed content length in bytes.
 func (bs *BitStringWriter) WriteContentLength(contentLength uint32) {
-	bs.Write(byte(CONTROL_BIT_CONTENT_LENGTH), 2)
+	bs.writeHeader(headerVersionTreeGrammar, contentLength)
+}
+
+// writeHeader writes the 2 bit header version followed by the trailing 30 bits
+// of contentLength.
+func (bs *BitStringWriter) writeHeader(version byte, contentLength uint32) {
+	bs.Write(version, 2)
 	// skip 2 bits
 	bits := byte((contentLength & (uint32(onesMask(6)) << 24)) >> 24)
 	bs.Write(bits, 6)
@@ -1,9 +1,11 @@
 package huffman
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
+	"github.com/mstergianis/huffman/pkg/bitio"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -180,3 +182,45 @@ func bitPattern(s string) (byte, int) {
 	}
 	panic(fmt.Sprintf("unsupported character passed into convertCharToBitPattern: %s", s))
 }
+
+func TestBitStringRead(t *testing.T) {
+	t.Run("reads across byte boundaries", func(t *testing.T) {
+		bsw := &BitStringWriter{}
+		widths := []int{3, 6, 7, 1, 5, 8, 2}
+		for i, w := range widths {
+			bsw.Write(byte(i+20)&onesMask(w), w)
+		}
+
+		bsr := NewBitStringReader(bsw.Bytes())
+		for i, w := range widths {
+			b, err := bsr.Read(w)
+			assert.NoError(t, err)
+			Equal(t, byte(i+20)&onesMask(w), b, "value %d, %d bits wide", i, w)
+		}
+	})
+}
+
+func TestBitStringWriterMatchesBitio(t *testing.T) {
+	buf := &bytes.Buffer{}
+	bw := bitio.NewWriter(buf)
+	bsw := &BitStringWriter{}
+	for i := range 200 {
+		width := i%8 + 1
+		value := byte(i*37) & onesMask(width)
+		bsw.Write(value, width)
+		assert.NoError(t, bw.WriteBits(uint64(value), width))
+	}
+	assert.NoError(t, bw.Flush())
+	Equal(t, bsw.Bytes(), buf.Bytes())
+
+	bs := NewBitStringReader(buf.Bytes())
+	br := bitio.NewReader(bytes.NewReader(buf.Bytes()))
+	for i := range 200 {
+		width := i%8 + 1
+		expected, err := bs.Read(width)
+		assert.NoError(t, err)
+		actual, err := br.ReadBits(width)
+		assert.NoError(t, err)
+		Equal(t, uint64(expected), actual, "read %d", i)
+	}
+}
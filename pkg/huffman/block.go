@@ -0,0 +1,389 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// A block stream splits its input into blocks of at most blockSize bytes and
+// encodes each of them independently with Encode. An index at the end of the
+// stream records where every block lives, so that a reader can decode any
+// block without touching the ones before it.
+//
+// layout:
+//
+//	stream                = header { block } endOfBlocks { indexEntry } trailer .
+//	header      (4 bytes) = blockMagic .
+//	block                 = length (4 bytes) payload (length bytes) .
+//	endOfBlocks (4 bytes) = 0x00000000 .
+//	indexEntry (20 bytes) = offset (8 bytes) length (4 bytes) size (4 bytes) crc32 (4 bytes) .
+//	trailer    (16 bytes) = indexOffset (8 bytes) count (4 bytes) indexMagic .
+//
+// All integers are big endian. offset and length locate a block's payload
+// within the stream, size is the length of the decoded block and crc32 is the
+// IEEE checksum of the decoded block.
+const (
+	DefaultBlockSize = 64 * 1024
+
+	blockHeaderLen     = 4
+	blockIndexEntryLen = 20
+	blockTrailerLen    = 16
+)
+
+var (
+	blockMagic = [4]byte{0x89, 'H', 'F', 'B'}
+	indexMagic = [4]byte{0x89, 'H', 'F', 'I'}
+)
+
+type blockIndexEntry struct {
+	offset int64
+	length uint32
+	size   uint32
+	crc    uint32
+}
+
+// BlockWriter encodes everything written to it as a block stream.
+//
+// Close must be called to encode the final block and write the index, it does
+// not close the underlying writer.
+type BlockWriter struct {
+	w          io.Writer
+	blockSize  int
+	buf        []byte
+	written    int64
+	index      []blockIndexEntry
+	verify     bool
+	endOfBlock bool
+	consumed   int64
+	progress   ProgressFunc
+	err        error
+}
+
+func NewBlockWriter(w io.Writer, blockSize int) *BlockWriter {
+	if blockSize < 1 {
+		blockSize = DefaultBlockSize
+	}
+	return &BlockWriter{w: w, blockSize: blockSize}
+}
+
+// VerifyBlocks makes bw decode every block right after encoding it, failing
+// with ErrVerifyMismatch if it does not decode back to what was written.
+func (bw *BlockWriter) VerifyBlocks() {
+	bw.verify = true
+}
+
+// EndOfBlock makes bw encode every block with EncodeEndOfBlock, ending its
+// content with the end of block symbol instead of storing its length. The
+// stream itself is unchanged, every block is still preceded by its length.
+func (bw *BlockWriter) EndOfBlock() {
+	bw.endOfBlock = true
+}
+
+// OnProgress makes bw call progress after every block it writes, and once
+// more when it is closed.
+func (bw *BlockWriter) OnProgress(progress ProgressFunc) {
+	bw.progress = progress
+}
+
+func (bw *BlockWriter) Write(p []byte) (int, error) {
+	if bw.err != nil {
+		return 0, bw.err
+	}
+
+	n := 0
+	for len(p) > 0 {
+		free := bw.blockSize - len(bw.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		bw.buf = append(bw.buf, p[:free]...)
+		p = p[free:]
+		n += free
+
+		if len(bw.buf) == bw.blockSize {
+			if err := bw.flushBlock(); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (bw *BlockWriter) Close() error {
+	if bw.err != nil {
+		return bw.err
+	}
+
+	if err := bw.flushBlock(); err != nil {
+		return err
+	}
+	if err := bw.writeHeader(); err != nil {
+		return err
+	}
+
+	indexOffset := bw.written + 4
+	out := make([]byte, 4, 4+len(bw.index)*blockIndexEntryLen+blockTrailerLen)
+	for _, e := range bw.index {
+		out = binary.BigEndian.AppendUint64(out, uint64(e.offset))
+		out = binary.BigEndian.AppendUint32(out, e.length)
+		out = binary.BigEndian.AppendUint32(out, e.size)
+		out = binary.BigEndian.AppendUint32(out, e.crc)
+	}
+	out = binary.BigEndian.AppendUint64(out, uint64(indexOffset))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(bw.index)))
+	out = append(out, indexMagic[:]...)
+	if err := bw.write(out); err != nil {
+		return err
+	}
+	bw.reportProgress()
+
+	bw.err = fmt.Errorf("error: write to a closed BlockWriter")
+	return nil
+}
+
+func (bw *BlockWriter) writeHeader() error {
+	if bw.written > 0 {
+		return nil
+	}
+	return bw.write(blockMagic[:])
+}
+
+func (bw *BlockWriter) flushBlock() error {
+	if len(bw.buf) == 0 {
+		return nil
+	}
+	if err := bw.writeHeader(); err != nil {
+		return err
+	}
+
+	encode := Encode
+	if bw.endOfBlock {
+		encode = EncodeEndOfBlock
+	}
+	payload, err := encode(bw.buf)
+	if err != nil {
+		bw.err = err
+		return err
+	}
+	if bw.verify {
+		decoded, err := Decode(payload)
+		if err != nil {
+			bw.err = fmt.Errorf("%w: block %d: %w", ErrVerifyMismatch, len(bw.index), err)
+			return bw.err
+		}
+		if !bytes.Equal(decoded, bw.buf) {
+			bw.err = fmt.Errorf("%w: block %d", ErrVerifyMismatch, len(bw.index))
+			return bw.err
+		}
+	}
+
+	entry := blockIndexEntry{
+		offset: bw.written + 4,
+		length: uint32(len(payload)),
+		size:   uint32(len(bw.buf)),
+		crc:    crc32.ChecksumIEEE(bw.buf),
+	}
+	if err := bw.write(binary.BigEndian.AppendUint32(nil, entry.length)); err != nil {
+		return err
+	}
+	if err := bw.write(payload); err != nil {
+		return err
+	}
+	bw.index = append(bw.index, entry)
+	bw.consumed += int64(len(bw.buf))
+	bw.buf = bw.buf[:0]
+	bw.reportProgress()
+
+	return nil
+}
+
+func (bw *BlockWriter) reportProgress() {
+	if bw.progress != nil {
+		bw.progress(Progress{Consumed: bw.consumed, Produced: bw.written})
+	}
+}
+
+func (bw *BlockWriter) write(p []byte) error {
+	n, err := bw.w.Write(p)
+	bw.written += int64(n)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		bw.err = err
+	}
+	return err
+}
+
+func parseBlockIndexEntry(e []byte) blockIndexEntry {
+	return blockIndexEntry{
+		offset: int64(binary.BigEndian.Uint64(e[0:8])),
+		length: binary.BigEndian.Uint32(e[8:12]),
+		size:   binary.BigEndian.Uint32(e[12:16]),
+		crc:    binary.BigEndian.Uint32(e[16:20]),
+	}
+}
+
+// IsBlockStream reports whether input starts like a block stream.
+func IsBlockStream(input []byte) bool {
+	return len(input) >= blockHeaderLen && bytes.Equal(input[:blockHeaderLen], blockMagic[:])
+}
+
+// decodeBlock decodes a block's payload and checks it against its index entry.
+func decodeBlock(payload []byte, e blockIndexEntry, i int) ([]byte, error) {
+	contents, err := Decode(payload)
+	if err != nil {
+		return nil, shiftOffset(fmt.Errorf("error: while decoding block %d: %w", i, err), e.offset)
+	}
+	if uint32(len(contents)) != e.size {
+		return nil, corruptInput(e.offset, "error: block %d decoded to %d bytes but the index expected %d", i, len(contents), e.size)
+	}
+	if crc32.ChecksumIEEE(contents) != e.crc {
+		return nil, &ChecksumError{Block: i, Offset: e.offset}
+	}
+	return contents, nil
+}
+
+// BlockReader decodes a block stream as it is read, one block at a time. The
+// index at the end of the stream is checked against the blocks that were read
+// once they have all been returned.
+type BlockReader struct {
+	r        io.Reader
+	read     int64
+	produced int64
+	index    []blockIndexEntry
+	contents []byte
+	progress ProgressFunc
+	err      error
+}
+
+func NewBlockReader(r io.Reader) *BlockReader {
+	return &BlockReader{r: r}
+}
+
+// OnProgress makes br call progress after every block it decodes, and once
+// more when it has checked the index.
+func (br *BlockReader) OnProgress(progress ProgressFunc) {
+	br.progress = progress
+}
+
+func (br *BlockReader) Read(p []byte) (int, error) {
+	for len(br.contents) == 0 {
+		if br.err != nil {
+			return 0, br.err
+		}
+		br.err = br.nextBlock()
+	}
+
+	n := copy(p, br.contents)
+	br.contents = br.contents[n:]
+	return n, nil
+}
+
+// nextBlock reads and decodes the next block into br.contents. It returns
+// io.EOF once the index has been read and checked.
+func (br *BlockReader) nextBlock() error {
+	if br.read == 0 {
+		header := make([]byte, blockHeaderLen)
+		if err := br.readFull(header); err != nil {
+			return err
+		}
+		if !IsBlockStream(header) {
+			return corruptInput(0, "error: input is not a block stream, header was %x", header)
+		}
+	}
+
+	length := make([]byte, 4)
+	if err := br.readFull(length); err != nil {
+		return err
+	}
+	entry := blockIndexEntry{
+		offset: br.read,
+		length: binary.BigEndian.Uint32(length),
+	}
+	if entry.length == 0 {
+		return br.checkIndex()
+	}
+
+	payload, err := br.readPayload(entry.length)
+	if err != nil {
+		return err
+	}
+	contents, err := Decode(payload)
+	if err != nil {
+		return shiftOffset(fmt.Errorf("error: while decoding block %d: %w", len(br.index), err), entry.offset)
+	}
+	entry.size = uint32(len(contents))
+	entry.crc = crc32.ChecksumIEEE(contents)
+	br.index = append(br.index, entry)
+	br.contents = contents
+	br.produced += int64(len(contents))
+	br.reportProgress()
+
+	return nil
+}
+
+func (br *BlockReader) reportProgress() {
+	if br.progress != nil {
+		br.progress(Progress{Consumed: br.read, Produced: br.produced})
+	}
+}
+
+func (br *BlockReader) checkIndex() error {
+	indexOffset := br.read
+	raw := make([]byte, len(br.index)*blockIndexEntryLen+blockTrailerLen)
+	if err := br.readFull(raw); err != nil {
+		return err
+	}
+
+	for i, seen := range br.index {
+		entry := parseBlockIndexEntry(raw[i*blockIndexEntryLen:])
+		if entry.crc != seen.crc {
+			return &ChecksumError{Block: i, Offset: seen.offset}
+		}
+		if entry != seen {
+			return corruptInput(indexOffset+int64(i*blockIndexEntryLen), "error: block %d does not match its index entry", i)
+		}
+	}
+
+	trailerOffset := indexOffset + int64(len(br.index)*blockIndexEntryLen)
+	trailer := raw[len(br.index)*blockIndexEntryLen:]
+	if [4]byte(trailer[12:]) != indexMagic {
+		return corruptInput(trailerOffset, "error: block stream trailer ended with %x, expected %x", trailer[12:], indexMagic)
+	}
+	if int64(binary.BigEndian.Uint64(trailer[0:8])) != indexOffset || int(binary.BigEndian.Uint32(trailer[8:12])) != len(br.index) {
+		return corruptInput(trailerOffset, "error: block stream trailer does not match the %d blocks that were read", len(br.index))
+	}
+	br.reportProgress()
+
+	return io.EOF
+}
+
+// readPayload reads the n bytes of a block's payload. n comes from the input, so
+// the payload grows as it arrives rather than being allocated up front.
+func (br *BlockReader) readPayload(n uint32) ([]byte, error) {
+	payload := &bytes.Buffer{}
+	read, err := io.Copy(payload, io.LimitReader(br.r, int64(n)))
+	br.read += read
+	if err != nil {
+		return nil, err
+	}
+	if read < int64(n) {
+		return nil, &CorruptInputError{Offset: br.read, Err: io.ErrUnexpectedEOF}
+	}
+	return payload.Bytes(), nil
+}
+
+func (br *BlockReader) readFull(p []byte) error {
+	n, err := io.ReadFull(br.r, p)
+	br.read += int64(n)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &CorruptInputError{Offset: br.read, Err: io.ErrUnexpectedEOF}
+	}
+	return err
+}
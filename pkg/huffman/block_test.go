@@ -0,0 +1,200 @@
+package huffman
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeBlockStream(t *testing.T, input []byte, blockSize int) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	bw := NewBlockWriter(buf, blockSize)
+	_, err := bw.Write(input)
+	assert.NoError(t, err)
+	assert.NoError(t, bw.Close())
+	return buf.Bytes()
+}
+
+func TestReaderAt(t *testing.T) {
+	input := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog. ", 50))
+	encoded := encodeBlockStream(t, input, 100)
+	Equal(t, true, IsBlockStream(encoded))
+
+	ra, err := OpenReaderAt(bytes.NewReader(encoded), int64(len(encoded)))
+	assert.NoError(t, err)
+	Equal(t, int64(len(input)), ra.Size())
+
+	t.Run("read within a block", func(t *testing.T) {
+		p := make([]byte, 20)
+		n, err := ra.ReadAt(p, 110)
+		assert.NoError(t, err)
+		Equal(t, 20, n)
+		Equal(t, input[110:130], p)
+	})
+
+	t.Run("read across blocks", func(t *testing.T) {
+		p := make([]byte, 350)
+		n, err := ra.ReadAt(p, 75)
+		assert.NoError(t, err)
+		Equal(t, 350, n)
+		Equal(t, input[75:425], p)
+	})
+
+	t.Run("read past the end", func(t *testing.T) {
+		p := make([]byte, 30)
+		n, err := ra.ReadAt(p, int64(len(input)-10))
+		Equal(t, io.EOF, err)
+		Equal(t, 10, n)
+		Equal(t, input[len(input)-10:], p[:n])
+
+		_, err = ra.ReadAt(p, int64(len(input)))
+		Equal(t, io.EOF, err)
+	})
+
+	t.Run("seek and read", func(t *testing.T) {
+		pos, err := ra.Seek(-500, io.SeekEnd)
+		assert.NoError(t, err)
+		Equal(t, int64(len(input)-500), pos)
+
+		rest, err := io.ReadAll(ra)
+		assert.NoError(t, err)
+		Equal(t, input[len(input)-500:], rest)
+	})
+
+	t.Run("read everything", func(t *testing.T) {
+		_, err := ra.Seek(0, io.SeekStart)
+		assert.NoError(t, err)
+
+		all, err := io.ReadAll(ra)
+		assert.NoError(t, err)
+		Equal(t, input, all)
+	})
+}
+
+func TestReaderAtChecksum(t *testing.T) {
+	input := []byte(strings.Repeat("abcabcabd", 30))
+	encoded := encodeBlockStream(t, input, 64)
+
+	ra, err := OpenReaderAt(bytes.NewReader(encoded), int64(len(encoded)))
+	assert.NoError(t, err)
+
+	// flip a bit in the first index entry's checksum
+	corrupted := bytes.Clone(encoded)
+	indexOffset := len(encoded) - blockTrailerLen - blockIndexEntryLen*len(ra.index)
+	corrupted[indexOffset+19] ^= 1
+
+	ra, err = OpenReaderAt(bytes.NewReader(corrupted), int64(len(corrupted)))
+	assert.NoError(t, err)
+	_, err = ra.ReadAt(make([]byte, 10), 0)
+	var checksumErr *ChecksumError
+	assert.ErrorAs(t, err, &checksumErr)
+	Equal(t, 0, checksumErr.Block)
+}
+
+func TestOpenReaderAtRejectsPlainStreams(t *testing.T) {
+	encoded, err := Encode([]byte("hello world, this is not a block stream"))
+	assert.NoError(t, err)
+	_, err = OpenReaderAt(bytes.NewReader(encoded), int64(len(encoded)))
+	assert.Error(t, err)
+}
+
+func TestBlockStreamInputs(t *testing.T) {
+	binary := make([]byte, 0, 256*4)
+	for i := range 256 * 4 {
+		binary = append(binary, byte(i))
+	}
+
+	testCases := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "every byte value", input: binary},
+		{name: "single symbol blocks", input: bytes.Repeat([]byte{0xff}, 300)},
+		{name: "single symbol last block", input: append(bytes.Repeat([]byte("ab"), 64), 'z')},
+		{name: "empty stream", input: []byte{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded := encodeBlockStream(t, tc.input, 128)
+			ra, err := OpenReaderAt(bytes.NewReader(encoded), int64(len(encoded)))
+			assert.NoError(t, err)
+			Equal(t, int64(len(tc.input)), ra.Size())
+
+			all, err := io.ReadAll(ra)
+			assert.NoError(t, err)
+			Equal(t, len(tc.input), len(all))
+			Equal(t, tc.input, all[:len(tc.input)])
+		})
+	}
+
+	t.Run("empty block", func(t *testing.T) {
+		payload, err := Encode([]byte{})
+		assert.NoError(t, err)
+		contents, err := decodeBlock(payload, blockIndexEntry{crc: crc32.ChecksumIEEE(nil)}, 0)
+		assert.NoError(t, err)
+		Equal(t, 0, len(contents))
+	})
+}
+
+func TestBlockReader(t *testing.T) {
+	t.Run("stream", func(t *testing.T) {
+		input := []byte(strings.Repeat("streams of bytes\x00\xff ", 40))
+		encoded := encodeBlockStream(t, input, 128)
+
+		decoded, err := io.ReadAll(NewBlockReader(bytes.NewReader(encoded)))
+		assert.NoError(t, err)
+		Equal(t, input, decoded)
+	})
+
+	t.Run("end of block", func(t *testing.T) {
+		input := []byte(strings.Repeat("blocks ended by a symbol ", 40))
+		buf := &bytes.Buffer{}
+		bw := NewBlockWriter(buf, 128)
+		bw.EndOfBlock()
+		_, err := bw.Write(input)
+		assert.NoError(t, err)
+		assert.NoError(t, bw.Close())
+
+		decoded, err := io.ReadAll(NewBlockReader(bytes.NewReader(buf.Bytes())))
+		assert.NoError(t, err)
+		Equal(t, input, decoded)
+	})
+
+	t.Run("empty stream", func(t *testing.T) {
+		encoded := encodeBlockStream(t, nil, 128)
+
+		decoded, err := io.ReadAll(NewBlockReader(bytes.NewReader(encoded)))
+		assert.NoError(t, err)
+		Equal(t, 0, len(decoded))
+	})
+
+	t.Run("truncated stream", func(t *testing.T) {
+		encoded := encodeBlockStream(t, []byte(strings.Repeat("abc", 100)), 128)
+
+		_, err := io.ReadAll(NewBlockReader(bytes.NewReader(encoded[:len(encoded)-1])))
+		var corruptErr *CorruptInputError
+		assert.ErrorAs(t, err, &corruptErr)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("block length beyond the input", func(t *testing.T) {
+		// a block claiming 4 GiB of payload must not be allocated up front
+		stream := append(bytes.Clone(blockMagic[:]), 0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, err := io.ReadAll(NewBlockReader(bytes.NewReader(stream)))
+		runtime.ReadMemStats(&after)
+
+		var corruptErr *CorruptInputError
+		assert.ErrorAs(t, err, &corruptErr)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		Equal(t, int64(len(stream)), corruptErr.Offset)
+		assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+	})
+}
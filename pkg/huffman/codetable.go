@@ -0,0 +1,100 @@
+package huffman
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+)
+
+// MaxCodeLength is the longest code a CodeTable can hold.
+const MaxCodeLength = 64
+
+// Code is the code of a symbol, the Length low bits of Bits read from the most
+// significant one down, 0 meaning left and 1 right.
+type Code struct {
+	Bits   uint64
+	Length int
+}
+
+// String writes the code as a string of '0's and '1's.
+func (c Code) String() string {
+	s := &strings.Builder{}
+	for i := c.Length - 1; i >= 0; i-- {
+		s.WriteByte('0' + byte(c.Bits>>i&1))
+	}
+	return s.String()
+}
+
+// CodeTable holds the code of every symbol of a tree, and of its end of block
+// leaf if it has one.
+type CodeTable struct {
+	codes      [256]Code
+	present    [256]bool
+	len        int
+	endOfBlock *Code
+}
+
+// NewCodeTable builds the code table of tree. It fails if a code would be
+// longer than MaxCodeLength bits, which trees built by Encode never are.
+func NewCodeTable(tree *Node) (*CodeTable, error) {
+	t := &CodeTable{}
+	if err := t.add(tree, Code{}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *CodeTable) add(n *Node, code Code) error {
+	if n == nil {
+		return nil
+	}
+	if n.freqPair != nil && n.freqPair.endOfBlock {
+		t.endOfBlock = &code
+		return nil
+	}
+	if n.freqPair != nil {
+		t.codes[n.freqPair.char] = code
+		t.present[n.freqPair.char] = true
+		t.len++
+		return nil
+	}
+	if code.Length == MaxCodeLength {
+		return fmt.Errorf("error: the tree has codes longer than %d bits", MaxCodeLength)
+	}
+	if err := t.add(n.left, Code{Bits: code.Bits << 1, Length: code.Length + 1}); err != nil {
+		return err
+	}
+	return t.add(n.right, Code{Bits: code.Bits<<1 | 1, Length: code.Length + 1})
+}
+
+// Lookup returns the code of symbol, and whether the table has one.
+func (t *CodeTable) Lookup(symbol byte) (Code, bool) {
+	return t.codes[symbol], t.present[symbol]
+}
+
+// EndOfBlock returns the code of the end of block leaf, and whether the tree
+// has one.
+func (t *CodeTable) EndOfBlock() (Code, bool) {
+	if t.endOfBlock == nil {
+		return Code{}, false
+	}
+	return *t.endOfBlock, true
+}
+
+// Len returns the number of symbols in the table, not counting the end of
+// block leaf.
+func (t *CodeTable) Len() int {
+	return t.len
+}
+
+// All iterates over the symbols of the table and their codes, in symbol
+// order.
+func (t *CodeTable) All() iter.Seq2[byte, Code] {
+	return func(yield func(byte, Code) bool) {
+		for symbol := range 256 {
+			if t.present[symbol] && !yield(byte(symbol), t.codes[symbol]) {
+				return
+			}
+		}
+	}
+}
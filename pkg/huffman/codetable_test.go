@@ -0,0 +1,63 @@
+package huffman
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeTable(t *testing.T) {
+	tree := NewNodeFromInput([]byte("aaaabbcd"))
+	table, err := NewCodeTable(tree)
+	assert.NoError(t, err)
+	Equal(t, 4, table.Len())
+
+	codes := tree.Codes()
+	for symbol, code := range table.All() {
+		Equal(t, codes[symbol], code.String())
+		Equal(t, len(codes[symbol]), code.Length)
+	}
+
+	code, ok := table.Lookup('a')
+	Equal(t, true, ok)
+	Equal(t, Code{Bits: 0, Length: 1}, code)
+	_, ok = table.Lookup('z')
+	Equal(t, false, ok)
+
+	var symbols []byte
+	for symbol := range table.All() {
+		symbols = append(symbols, symbol)
+	}
+	Equal(t, []byte("abcd"), symbols)
+
+	_, ok = table.EndOfBlock()
+	Equal(t, false, ok)
+}
+
+func TestCodeTableEndOfBlock(t *testing.T) {
+	tree := NewNode(append(computeFreqTable([]byte("aaaabbcd")), freqPair{freq: 1, endOfBlock: true}))
+	table, err := NewCodeTable(tree)
+	assert.NoError(t, err)
+	Equal(t, 4, table.Len())
+
+	code, ok := table.EndOfBlock()
+	Equal(t, true, ok)
+	// ties with 'c' and 'd' go to the bytes first
+	Equal(t, 3, code.Length)
+}
+
+func TestCodeString(t *testing.T) {
+	Equal(t, "", Code{}.String())
+	Equal(t, "0101", Code{Bits: 0b0101, Length: 4}.String())
+	Equal(t, "0001", Code{Bits: 1, Length: 4}.String())
+}
+
+func TestCodeTableTooDeep(t *testing.T) {
+	// a tree that leans right, giving its deepest leaves 65 bit codes
+	tree := &Node{freqPair: &freqPair{char: 0}}
+	for i := 1; i <= MaxCodeLength+1; i++ {
+		tree = &Node{left: &Node{freqPair: &freqPair{char: byte(i)}}, right: tree}
+	}
+	_, err := NewCodeTable(tree)
+	assert.Error(t, err)
+}
@@ -0,0 +1,318 @@
+package huffman
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/mstergianis/huffman/pkg/bitio"
+)
+
+// Encoded data starts with a 2 bit header version, followed by the 30 bit
+// content length. Version 00 stores the tree in the grammar described by
+// Node.WriteBytes, version 01 in one of the compact forms below.
+//
+// Version 10 has no content length, the content ends with the code of the end
+// of block symbol instead. Its tree is an endOfBlockTree.
+const (
+	headerVersionTreeGrammar byte = 0b00
+	headerVersionCompactTree byte = 0b01
+	headerVersionEndOfBlock  byte = 0b10
+)
+
+// A compact tree takes one of two forms, told apart by its first bit.
+//
+// layout:
+//
+//	compactTree         = preorderTree | bitmapTree .
+//	preorderTree        = "0" shape { symbol (8 bits) } .
+//	shape               = "1" shape shape | "0" .
+//	bitmapTree          = "1" present (256 bits) { codeLength (6 bits) } .
+//	endOfBlockTree      = shape endOfBlockLeaf (9 bits) { symbol (8 bits) } .
+//
+// A preorder tree writes one bit per node in preorder, 1 for an internal node
+// and 0 for a leaf, followed by the symbol of every leaf in the same order. It
+// takes 10n-1 bits for n leaves, where the tree grammar takes 14n-4.
+//
+// A bitmap tree sets the bit of every symbol present in the tree, from symbol
+// 0 up, followed by the code length of every present symbol in the same
+// order. The tree is the canonical one for those code lengths, see
+// canonicalTree. It takes 256+6n bits, which is smaller from 65 leaves on.
+//
+// An end of block tree has one leaf more than there are symbols, the end of
+// block leaf. It is a preorder tree without the form bit, with the index of
+// the end of block leaf among the leaves written between the shape and the
+// symbols of the other leaves.
+const (
+	compactTreePreorder byte = 0
+	compactTreeBitmap   byte = 1
+
+	codeLengthBits     = 6
+	endOfBlockLeafBits = 9
+	maxLeaves          = 256
+)
+
+// writeCompactTree writes tree in whichever compact form is smaller, and
+// returns the tree that content must be encoded with: tree itself, or the
+// canonical tree with the same code lengths. Write errors are kept by bw.
+func writeCompactTree(bw *bitio.Writer, tree *Node) *Node {
+	var lengths []symbolLength
+	tree.Walk(func(n *Node, depth int, _ []byte) bool {
+		if n.IsLeaf() {
+			lengths = append(lengths, symbolLength{symbol: n.Symbol(), length: depth})
+		}
+		return true
+	})
+	maxLength := slices.MaxFunc(lengths, func(a, b symbolLength) int {
+		return cmp.Compare(a.length, b.length)
+	}).length
+
+	if bitmapTreeBits(len(lengths)) < preorderTreeBits(len(lengths)) && maxLength < 1<<codeLengthBits {
+		bw.WriteBits(uint64(compactTreeBitmap), 1)
+		slices.SortFunc(lengths, func(a, b symbolLength) int {
+			return cmp.Compare(a.symbol, b.symbol)
+		})
+		var present [maxLeaves / 8]byte
+		for _, l := range lengths {
+			present[l.symbol/8] |= 1 << (7 - l.symbol%8)
+		}
+		for _, b := range present {
+			bw.WriteBits(uint64(b), 8)
+		}
+		for _, l := range lengths {
+			bw.WriteBits(uint64(l.length), codeLengthBits)
+		}
+		canonical, _ := canonicalTree(lengths)
+		return canonical
+	}
+
+	bw.WriteBits(uint64(compactTreePreorder), 1)
+	writeShape(bw, tree)
+	for leaf := range tree.Leaves() {
+		bw.WriteBits(uint64(leaf.Symbol()), 8)
+	}
+	return tree
+}
+
+// writeEndOfBlockTree writes a tree holding the end of block leaf as an end of
+// block tree. Write errors are kept by bw.
+func writeEndOfBlockTree(bw *bitio.Writer, tree *Node) {
+	writeShape(bw, tree)
+	index := 0
+	for leaf := range tree.Leaves() {
+		if leaf.IsEndOfBlock() {
+			break
+		}
+		index++
+	}
+	bw.WriteBits(uint64(index), endOfBlockLeafBits)
+	for leaf := range tree.Leaves() {
+		if !leaf.IsEndOfBlock() {
+			bw.WriteBits(uint64(leaf.Symbol()), 8)
+		}
+	}
+}
+
+// writeShape writes one bit per node of tree in preorder, 1 for an internal
+// node and 0 for a leaf.
+func writeShape(bw *bitio.Writer, tree *Node) {
+	tree.Walk(func(n *Node, _ int, _ []byte) bool {
+		if n.IsLeaf() {
+			bw.WriteBit(0)
+		} else {
+			bw.WriteBit(1)
+		}
+		return true
+	})
+}
+
+// compactTreeBits returns the length in bits of the smallest compact tree with
+// the given number of leaves.
+func compactTreeBits(leaves int) int {
+	return 1 + min(preorderTreeBits(leaves), bitmapTreeBits(leaves))
+}
+
+func preorderTreeBits(leaves int) int {
+	return 2*leaves - 1 + 8*leaves
+}
+
+func bitmapTreeBits(leaves int) int {
+	return maxLeaves + codeLengthBits*leaves
+}
+
+// readCompactTree reads a tree written by writeCompactTree.
+func readCompactTree(bs *BitStringReader) (*Node, error) {
+	form, err := bs.Read(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if form == compactTreeBitmap {
+		var lengths []symbolLength
+		for i := range maxLeaves / 8 {
+			b, err := bs.Read(8)
+			if err != nil {
+				return nil, err
+			}
+			for bit := range 8 {
+				if b&(1<<(7-bit)) != 0 {
+					lengths = append(lengths, symbolLength{symbol: byte(i*8 + bit)})
+				}
+			}
+		}
+		for i := range lengths {
+			length, err := bs.Read(codeLengthBits)
+			if err != nil {
+				return nil, err
+			}
+			lengths[i].length = int(length)
+		}
+		return canonicalTree(lengths)
+	}
+
+	leaves := 0
+	tree, err := readShape(bs, 0, &leaves, maxLeaves)
+	if err != nil {
+		return nil, err
+	}
+	for leaf := range tree.Leaves() {
+		symbol, err := bs.Read(8)
+		if err != nil {
+			return nil, err
+		}
+		leaf.freqPair.char = symbol
+	}
+	if err := validateTree(tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// readEndOfBlockTree reads a tree written by writeEndOfBlockTree.
+func readEndOfBlockTree(bs *BitStringReader) (*Node, error) {
+	leaves := 0
+	tree, err := readShape(bs, 0, &leaves, maxLeaves+1)
+	if err != nil {
+		return nil, err
+	}
+	high, err := bs.Read(endOfBlockLeafBits - 8)
+	if err != nil {
+		return nil, err
+	}
+	low, err := bs.Read(8)
+	if err != nil {
+		return nil, err
+	}
+	index := int(high)<<8 | int(low)
+	if index >= leaves {
+		return nil, fmt.Errorf("error: the end of block leaf is leaf %d of a tree with %d leaves", index, leaves)
+	}
+
+	i := 0
+	for leaf := range tree.Leaves() {
+		if i == index {
+			leaf.freqPair.endOfBlock = true
+		} else {
+			symbol, err := bs.Read(8)
+			if err != nil {
+				return nil, err
+			}
+			leaf.freqPair.char = symbol
+		}
+		i++
+	}
+	if err := validateTree(tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// readShape reads the shape of a preorder tree, counting its leaves and
+// limiting its depth and number of leaves so that a corrupt shape can't grow
+// past what a valid tree could be.
+func readShape(bs *BitStringReader, depth int, leaves *int, maxLeafCount int) (*Node, error) {
+	if depth > MaxCodeLength {
+		return nil, fmt.Errorf("error: the tree is deeper than %d levels", MaxCodeLength)
+	}
+	bit, err := bs.Read(1)
+	if err != nil {
+		return nil, err
+	}
+	if bit == 0 {
+		*leaves++
+		if *leaves > maxLeafCount {
+			return nil, fmt.Errorf("error: the tree has more than %d leaves", maxLeafCount)
+		}
+		return &Node{freqPair: &freqPair{}}, nil
+	}
+
+	n := &Node{}
+	if n.left, err = readShape(bs, depth+1, leaves, maxLeafCount); err != nil {
+		return nil, err
+	}
+	if n.right, err = readShape(bs, depth+1, leaves, maxLeafCount); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+type symbolLength struct {
+	symbol byte
+	length int
+}
+
+// canonicalTree builds the canonical tree for the given code lengths: codes
+// are handed out in order of length, then symbol, each one the next binary
+// number after the one before it. The lengths must describe a complete tree.
+func canonicalTree(lengths []symbolLength) (*Node, error) {
+	lengths = slices.Clone(lengths)
+	slices.SortFunc(lengths, func(a, b symbolLength) int {
+		return cmp.Or(cmp.Compare(a.length, b.length), cmp.Compare(a.symbol, b.symbol))
+	})
+
+	if len(lengths) == 0 {
+		return nil, fmt.Errorf("error: the tree has no symbols")
+	}
+	if len(lengths) == 1 {
+		if lengths[0].length != 0 {
+			return nil, fmt.Errorf("error: the only symbol of the tree has a code length of %d", lengths[0].length)
+		}
+		return &Node{freqPair: &freqPair{char: lengths[0].symbol}}, nil
+	}
+
+	root := &Node{}
+	var (
+		code   uint64
+		length int
+	)
+	for i, l := range lengths {
+		if l.length == 0 {
+			return nil, fmt.Errorf("error: symbol %q has a code length of 0", l.symbol)
+		}
+		if i > 0 {
+			code++
+		}
+		code <<= l.length - length
+		length = l.length
+		if code>>length != 0 {
+			return nil, fmt.Errorf("error: the code lengths of the tree do not fit a tree")
+		}
+
+		n := root
+		for bit := length - 1; bit >= 0; bit-- {
+			next := &n.left
+			if code>>bit&1 == 1 {
+				next = &n.right
+			}
+			if *next == nil {
+				*next = &Node{}
+			}
+			n = *next
+		}
+		n.freqPair = &freqPair{char: l.symbol}
+	}
+	if code != 1<<length-1 {
+		return nil, fmt.Errorf("error: the code lengths of the tree leave codes unused")
+	}
+	return root, nil
+}
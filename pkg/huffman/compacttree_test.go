@@ -0,0 +1,147 @@
+package huffman
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mstergianis/huffman/pkg/bitio"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeTreeGrammar encodes input as Encode did before compact trees, with a
+// version 00 header.
+func encodeTreeGrammar(input []byte) []byte {
+	bs := &BitStringWriter{}
+	bs.WriteContentLength(uint32(len(input)))
+	tree := NewNodeFromInput(input)
+	tree.WriteBytes(bs)
+	for _, b := range input {
+		bytes, bitWidth := tree.Search(b)
+		bs.WriteBytes(bytes, bitWidth)
+	}
+	return bs.Bytes()
+}
+
+func TestCompactTree(t *testing.T) {
+	everySymbol := make([]byte, 0, 1024)
+	for i := range 1024 {
+		everySymbol = append(everySymbol, byte(i*7))
+	}
+
+	testCases := []struct {
+		name  string
+		input []byte
+		form  byte
+	}{
+		{name: "single symbol", input: []byte("aaaa"), form: compactTreePreorder},
+		{name: "hello world", input: []byte("hello world"), form: compactTreePreorder},
+		{name: "every symbol", input: everySymbol, form: compactTreeBitmap},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tree := NewNodeFromInput(tc.input)
+			buf := &bytes.Buffer{}
+			bw := bitio.NewWriter(buf)
+			written := writeCompactTree(bw, tree)
+			assert.NoError(t, bw.Flush())
+			Equal(t, tc.form, buf.Bytes()[0]>>7)
+
+			// a canonical tree may hand out different codes, of the same
+			// lengths
+			lengths := func(n *Node) map[byte]int {
+				l := map[byte]int{}
+				for symbol, code := range n.AllCodes() {
+					l[symbol] = len(code)
+				}
+				return l
+			}
+			Equal(t, lengths(tree), lengths(written))
+
+			read, err := readCompactTree(NewBitStringReader(buf.Bytes()))
+			assert.NoError(t, err)
+			Equal(t, written.Codes(), read.Codes())
+		})
+	}
+}
+
+func TestEndOfBlockTree(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		input := []byte("abracadabra")
+		tree := NewNode(append(computeFreqTable(input), freqPair{freq: 1, endOfBlock: true}))
+		buf := &bytes.Buffer{}
+		bw := bitio.NewWriter(buf)
+		writeEndOfBlockTree(bw, tree)
+		assert.NoError(t, bw.Flush())
+
+		read, err := readEndOfBlockTree(NewBitStringReader(buf.Bytes()))
+		assert.NoError(t, err)
+		expected, err := NewCodeTable(tree)
+		assert.NoError(t, err)
+		actual, err := NewCodeTable(read)
+		assert.NoError(t, err)
+		Equal(t, expected, actual)
+	})
+
+	t.Run("end of block leaf out of range", func(t *testing.T) {
+		// a root with two leaves, the end of block being leaf 2
+		buf := &bytes.Buffer{}
+		bw := bitio.NewWriter(buf)
+		bw.WriteBits(0b100, 3)
+		bw.WriteBits(2, endOfBlockLeafBits)
+		bw.WriteBits('a', 8)
+		bw.WriteBits('b', 8)
+		assert.NoError(t, bw.Flush())
+
+		_, err := readEndOfBlockTree(NewBitStringReader(buf.Bytes()))
+		assert.ErrorContains(t, err, "end of block leaf is leaf 2")
+	})
+}
+
+func TestDecodeTreeGrammar(t *testing.T) {
+	input := []byte("files written before compact trees still decode")
+	decoded, err := Decode(encodeTreeGrammar(input))
+	assert.NoError(t, err)
+	Equal(t, input, decoded)
+}
+
+func TestCanonicalTree(t *testing.T) {
+	tree, err := canonicalTree([]symbolLength{{'c', 2}, {'a', 1}, {'b', 2}})
+	assert.NoError(t, err)
+	Equal(t, map[byte]string{'a': "0", 'b': "10", 'c': "11"}, tree.Codes())
+
+	invalid := map[string][]symbolLength{
+		"no symbols":         nil,
+		"lone symbol":        {{'a', 1}},
+		"zero length":        {{'a', 0}, {'b', 1}},
+		"too many codes":     {{'a', 1}, {'b', 1}, {'c', 1}},
+		"codes left unused":  {{'a', 1}, {'b', 2}},
+		"overlong codes":     {{'a', 2}, {'b', 2}, {'c', 2}, {'d', 2}, {'e', 2}},
+		"unused deep branch": {{'a', 1}, {'b', 3}, {'c', 3}},
+	}
+	for name, lengths := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := canonicalTree(lengths)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestCompactTreeSavings measures compact trees against the tree grammar, run
+// it with -v to see the sizes.
+func TestCompactTreeSavings(t *testing.T) {
+	inputs := []string{
+		"a",
+		"ab",
+		"hello world",
+		"the quick brown fox jumps over the lazy dog",
+		strings.Repeat("abcdefghijklmnopqrstuvwxyz0123456789", 4),
+	}
+	for _, input := range inputs {
+		encoded, err := Encode([]byte(input))
+		assert.NoError(t, err)
+		legacy := encodeTreeGrammar([]byte(input))
+		assert.LessOrEqual(t, len(encoded), len(legacy))
+		t.Logf("%4d bytes in, %4d bytes with the tree grammar, %4d with a compact tree", len(input), len(legacy), len(encoded))
+	}
+}
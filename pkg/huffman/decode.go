@@ -3,73 +3,156 @@ package huffman
 import (
 	"bytes"
 	"fmt"
+	"math"
 )
 
 func Decode(input []byte) ([]byte, error) {
+	contents, _, err := decode(input, nil)
+	return contents, err
+}
+
+// DecodeProgress is Decode, calling progress, if it is not nil, as input is
+// decoded.
+func DecodeProgress(input []byte, progress ProgressFunc) ([]byte, error) {
+	contents, _, err := decode(input, progress)
+	return contents, err
+}
+
+// decode decodes input, also returning the reader it was read with so that
+// the caller can tell how much of input was used.
+func decode(input []byte, progress ProgressFunc) ([]byte, *BitStringReader, error) {
 	if input == nil || len(input) < 1 {
-		return nil, fmt.Errorf("error: while decoding the input was empty")
+		return nil, nil, corruptInput(0, "error: while decoding the input was empty")
 	}
 	bs := NewBitStringReader(input)
 
-	// read in the content length
-	contentLength, err := bs.ReadContentLength()
+	contentLength, tree, err := ReadHeader(bs)
 	if err != nil {
-		return nil, err
+		return nil, bs, err
+	}
+	if contentLength == 0 {
+		if progress != nil {
+			progress(Progress{Consumed: int64(len(input))})
+		}
+		return []byte{}, bs, nil
 	}
-
-	// read in tree
-	tree := NewNodeFromBytes(bs)
 
 	// read in content
-	contents, err := ReadContent(bs, tree, contentLength)
+	contents, err := readContent(bs, tree, contentLength, progress)
 	if err != nil {
-		return nil, err
+		return nil, bs, &CorruptInputError{Offset: int64(bs.currentByte), Err: err}
+	}
+	if progress != nil {
+		progress(Progress{Consumed: int64(len(input)), Produced: int64(len(contents))})
 	}
 
-	return contents, nil
+	return contents, bs, nil
 }
 
-func (bs *BitStringReader) ReadContentLength() (ret uint32, err error) {
-	bits, err := bs.Read(2)
+// UnknownContentLength is the content length ReadHeader returns for data
+// encoded by EncodeEndOfBlock, whose content runs up to the end of block leaf.
+const UnknownContentLength = math.MaxUint32
+
+// ReadHeader reads the header and the tree at the start of encoded data,
+// leaving bs at the start of the content. The tree is nil when the content is
+// empty, except for data encoded by EncodeEndOfBlock, whose tree always holds
+// at least the end of block leaf. Errors are CorruptInputErrors.
+func ReadHeader(bs *BitStringReader) (contentLength uint32, tree *Node, err error) {
+	if bs == nil {
+		return 0, nil, corruptInput(0, "error: while decoding the input was empty")
+	}
+	version, contentLength, err := bs.readHeader()
 	if err != nil {
-		return 0, err
+		return 0, nil, &CorruptInputError{Offset: int64(bs.currentByte), Err: err}
 	}
-	if ControlBit(bits) != CONTROL_BIT_CONTENT_LENGTH {
-		return 0, fmt.Errorf("error: decoding expected first 2 bits to be the ControlBit header %02b", bits)
+	if contentLength == 0 {
+		return 0, nil, nil
 	}
 
-	bits, err = bs.Read(6)
+	switch version {
+	case headerVersionTreeGrammar:
+		tree, err = NewNodeFromBytes(bs)
+	case headerVersionCompactTree:
+		tree, err = readCompactTree(bs)
+	case headerVersionEndOfBlock:
+		tree, err = readEndOfBlockTree(bs)
+	}
+	if err != nil {
+		return 0, nil, &CorruptInputError{Offset: int64(bs.currentByte), Err: err}
+	}
+	return contentLength, tree, nil
+}
+
+// ReadContentLength reads a version 00 header, whose tree is read with
+// NewNodeFromBytes. ReadHeader reads every version.
+func (bs *BitStringReader) ReadContentLength() (uint32, error) {
+	version, contentLength, err := bs.readHeader()
 	if err != nil {
 		return 0, err
 	}
+	if version != headerVersionTreeGrammar {
+		return 0, fmt.Errorf("error: decoding expected first 2 bits to be the ControlBit header %02b", headerVersionTreeGrammar)
+	}
+	return contentLength, nil
+}
+
+func (bs *BitStringReader) readHeader() (version byte, ret uint32, err error) {
+	version, err = bs.Read(2)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch version {
+	case headerVersionEndOfBlock:
+		return version, UnknownContentLength, nil
+	case headerVersionTreeGrammar, headerVersionCompactTree:
+	default:
+		return 0, 0, fmt.Errorf("error: unknown header version %02b", version)
+	}
+
+	bits, err := bs.Read(6)
+	if err != nil {
+		return 0, 0, err
+	}
 	ret = ret | (uint32(bits) << 24)
 
 	bits, err = bs.Read(8)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	ret = ret | (uint32(bits) << 16)
 
 	bits, err = bs.Read(8)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	ret = ret | (uint32(bits) << 8)
 
 	bits, err = bs.Read(8)
 	if err != nil {
-		return 0, err
+		return 0, 0, err
 	}
 	ret = ret | uint32(bits)
 
-	return ret, nil
+	return version, ret, nil
 }
 
+// ReadContent decodes contentLength bytes of content with tree, or with
+// UnknownContentLength every byte up to the end of block leaf. Reaching the end
+// of block leaf always ends the content.
 func ReadContent(bs *BitStringReader, tree *Node, contentLength uint32) ([]byte, error) {
-	buf := &bytes.Buffer{}
+	return readContent(bs, tree, contentLength, nil)
+}
+
+func readContent(bs *BitStringReader, tree *Node, contentLength uint32, progress ProgressFunc) ([]byte, error) {
+	// content ended by the end of block leaf may be empty, which decodes to
+	// an empty slice as it does with a content length of 0
+	buf := bytes.NewBuffer([]byte{})
 	// read one bit at a time until you reach a leaf node, then write that byte.
-	var readBytes uint32 = 0
-	for readBytes < contentLength {
+	var readBytes int64 = 0
+	for contentLength == UnknownContentLength || readBytes < int64(contentLength) {
+		if progress != nil && readBytes > 0 && readBytes%progressInterval == 0 {
+			progress(Progress{Consumed: int64(bs.currentByte), Produced: readBytes})
+		}
 		n := tree
 		for n.freqPair == nil {
 			bit, err := bs.Read(1)
@@ -82,6 +165,12 @@ func ReadContent(bs *BitStringReader, tree *Node, contentLength uint32) ([]byte,
 			case RIGHT:
 				n = n.right
 			}
+			if n == nil {
+				return nil, fmt.Errorf("error: the content led to a missing branch of the tree")
+			}
+		}
+		if n.freqPair.endOfBlock {
+			break
 		}
 		readBytes++
 		err := buf.WriteByte(n.freqPair.char)
@@ -98,63 +187,144 @@ const (
 	RIGHT byte = 1
 )
 
-func NewNodeFromBytes(bs *BitStringReader) *Node {
+// NewNodeFromBytes reads a tree written by Node.WriteBytes. The tree is read
+// without recursion and checked with validateTree, so corrupt or hostile input
+// is rejected rather than producing a tree that can't be decoded with.
+func NewNodeFromBytes(bs *BitStringReader) (*Node, error) {
 	if bs == nil {
-		return nil
+		return nil, nil
 	}
-	var tree *Node = &Node{}
-	newNodeFromBytes(bs, tree)
-
-	return tree
-}
 
-func newNodeFromBytes(bs *BitStringReader, n *Node) error {
-	if n == nil || bs == nil {
-		return nil
+	// every internal node on the way down to the node being read, waiting for
+	// its second child
+	type pendingChild struct {
+		node    *Node
+		second  ControlBit
+		started bool
 	}
+	var stack []pendingChild
 
-	bits, err := bs.Read(2)
-	if err != nil {
-		return err
-	}
-	controlBits := ControlBit(bits)
-	if controlBits == CONTROL_BIT_FREQ_PAIR {
-		char, err := bs.Read(8)
+	root := &Node{}
+	n := root
+	for {
+		bits, err := bs.Read(2)
+		if err != nil {
+			return nil, err
+		}
+
+		switch controlBits := ControlBit(bits); controlBits {
+		case CONTROL_BIT_LEFT, CONTROL_BIT_RIGHT:
+			if len(stack) == MaxCodeLength {
+				return nil, fmt.Errorf("error: the tree is deeper than %d levels", MaxCodeLength)
+			}
+			second := CONTROL_BIT_RIGHT
+			if controlBits == CONTROL_BIT_RIGHT {
+				second = CONTROL_BIT_LEFT
+			}
+			stack = append(stack, pendingChild{node: n, second: second})
+			n = n.addChild(controlBits)
+			continue
+		case CONTROL_BIT_FREQ_PAIR:
+			char, err := bs.Read(8)
+			if err != nil {
+				return nil, err
+			}
+			n.freqPair = &freqPair{char: char}
+		default:
+			return nil, fmt.Errorf("error: expected the control bits of a tree node, received %02b", bits)
+		}
+
+		// the leaf completes every node above it that already has both of
+		// its children, the nearest one that doesn't reads its second child
+		// next
+		for len(stack) > 0 && stack[len(stack)-1].started {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			break
+		}
+		parent := &stack[len(stack)-1]
+		bits, err = bs.Read(2)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if ControlBit(bits) != parent.second {
+			return nil, fmt.Errorf("error: expected the control bits %02b (%s) of a node's second child, received %02b", byte(parent.second), parent.second, bits)
 		}
-		n.freqPair = &freqPair{char: char}
-		return nil
+		parent.started = true
+		n = parent.node.addChild(parent.second)
+	}
+
+	if err := validateTree(root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// addChild adds an empty child to n on the side controlBits names, and
+// returns it.
+func (n *Node) addChild(controlBits ControlBit) *Node {
+	child := &Node{}
+	if controlBits == CONTROL_BIT_LEFT {
+		n.left = child
+	} else {
+		n.right = child
+	}
+	return child
+}
+
+// validateTree checks that a tree read from encoded data can be decoded with:
+// every node is either a leaf or has two children, no symbol is in two leaves,
+// and no code is longer than MaxCodeLength bits.
+func validateTree(tree *Node) error {
+	if tree == nil {
+		return fmt.Errorf("error: the tree is empty")
 	}
 
 	var (
-		remainingNode       **Node
-		remainingControlBit ControlBit
+		seen       [256]bool
+		leaves     int
+		endOfBlock bool
+		err        error
 	)
-	switch controlBits {
-	case CONTROL_BIT_LEFT:
-		n.left = &Node{}
-		newNodeFromBytes(bs, n.left)
-		remainingNode = &n.right
-		remainingControlBit = CONTROL_BIT_RIGHT
-	case CONTROL_BIT_RIGHT:
-		n.right = &Node{}
-		newNodeFromBytes(bs, n.right)
-		remainingNode = &n.left
-		remainingControlBit = CONTROL_BIT_LEFT
+	tree.Walk(func(n *Node, depth int, path []byte) bool {
+		switch {
+		case err != nil:
+		case depth > MaxCodeLength:
+			err = fmt.Errorf("error: the tree is deeper than %d levels", MaxCodeLength)
+		case n.freqPair != nil && (n.left != nil || n.right != nil):
+			err = fmt.Errorf("error: the leaf of symbol %q has children", n.freqPair.char)
+		case n.freqPair != nil && n.freqPair.endOfBlock && endOfBlock:
+			err = fmt.Errorf("error: the tree has more than one end of block leaf")
+		case n.freqPair != nil && n.freqPair.endOfBlock:
+			endOfBlock = true
+		case n.freqPair == nil && (n.left == nil || n.right == nil):
+			err = fmt.Errorf("error: the internal node at %s is missing a child", pathString(path))
+		case n.freqPair != nil && seen[n.freqPair.char]:
+			err = fmt.Errorf("error: the symbol %q is in more than one leaf", n.freqPair.char)
+		case n.freqPair != nil:
+			seen[n.freqPair.char] = true
+			leaves++
+		}
+		return err == nil
+	})
+	if err == nil && leaves > maxLeaves {
+		err = fmt.Errorf("error: the tree has %d leaves, more than there are symbols", leaves)
 	}
+	return err
+}
 
-	bits, err = bs.Read(2)
-	if err != nil {
-		return err
+// pathString writes a path of LEFT and RIGHT steps as '0's and '1's, the root
+// being "the root".
+func pathString(path []byte) string {
+	if len(path) == 0 {
+		return "the root"
 	}
-	controlBits = ControlBit(bits)
-	if controlBits != remainingControlBit {
-		panic(fmt.Sprintf("error: newNodeFromBytes did not get the expected control bits %02b (%s) received %02b", byte(remainingControlBit), remainingControlBit, controlBits))
+	code := make([]byte, len(path))
+	for i, step := range path {
+		code[i] = '0' + step
 	}
-	*remainingNode = &Node{}
-	newNodeFromBytes(bs, *remainingNode)
-	return nil
+	return string(code)
 }
 
 type ControlBit byte
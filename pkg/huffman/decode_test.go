@@ -41,14 +41,16 @@ func TestReadContent(t *testing.T) {
 func TestNewNodeFromBytes(t *testing.T) {
 	t.Run("empty input", func(t *testing.T) {
 		bs := NewBitStringReader([]byte{})
-		n := NewNodeFromBytes(bs)
+		n, err := NewNodeFromBytes(bs)
+		assert.NoError(t, err)
 		Equal(t, nil, n)
 	})
 
 	t.Run("single node tree", func(t *testing.T) {
 		input := []byte{0b0101_1100, 0b1000_0000}
 		bs := NewBitStringReader(input)
-		n := NewNodeFromBytes(bs)
+		n, err := NewNodeFromBytes(bs)
+		assert.NoError(t, err)
 
 		expected := &Node{freqPair: &freqPair{char: 'r'}}
 		Equal(t, expected, n, "expected: %08b actual: %08b", expected.freqPair.char, n.freqPair.char)
@@ -61,7 +63,8 @@ func TestNewNodeFromBytes(t *testing.T) {
 			0b0111_0010,
 		}
 		bs := NewBitStringReader(input)
-		n := NewNodeFromBytes(bs)
+		n, err := NewNodeFromBytes(bs)
+		assert.NoError(t, err)
 		expected := &Node{
 			left: &Node{
 				freqPair: &freqPair{char: 'l'},
@@ -80,7 +83,8 @@ func TestNewNodeFromBytes(t *testing.T) {
 			0b0110_1100,
 		}
 		bs := NewBitStringReader(input)
-		n := NewNodeFromBytes(bs)
+		n, err := NewNodeFromBytes(bs)
+		assert.NoError(t, err)
 		expected := &Node{
 			left: &Node{
 				freqPair: &freqPair{char: 'l'},
@@ -144,7 +148,97 @@ func TestNewNodeFromBytes(t *testing.T) {
 			},
 		}
 		bs := NewBitStringReader(input)
-		n := NewNodeFromBytes(bs)
+		n, err := NewNodeFromBytes(bs)
+		assert.NoError(t, err)
 		Equal(t, expected, n)
 	})
 }
+
+func TestDecodeCorruptInput(t *testing.T) {
+	encoded, err := Encode([]byte("hello world"))
+	assert.NoError(t, err)
+	endOfBlock, err := EncodeEndOfBlock([]byte("hello world"))
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "empty input", input: []byte{}},
+		{name: "bad header", input: []byte{0xff, 0x00, 0x00, 0x0b}},
+		{name: "truncated header", input: encoded[:3]},
+		{name: "truncated content", input: encoded[:len(encoded)-2]},
+		{name: "content missing its end of block", input: endOfBlock[:len(endOfBlock)-1]},
+		{name: "unknown header version", input: []byte{0b1100_0000, 0x00, 0x00, 0x0b}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(tc.input)
+			var corruptErr *CorruptInputError
+			assert.ErrorAs(t, err, &corruptErr)
+		})
+	}
+}
+
+func TestNewNodeFromBytesRejectsInvalidTrees(t *testing.T) {
+	// writeTree writes the control bits and symbols of a tree in the grammar
+	// of Node.WriteBytes, where a string is a run of 2 bit control codes and a
+	// byte is a symbol
+	writeTree := func(parts ...any) []byte {
+		bs := &BitStringWriter{}
+		for _, part := range parts {
+			switch part := part.(type) {
+			case string:
+				for i := 0; i < len(part); i += 2 {
+					bs.Write((part[i]-'0')<<1|(part[i+1]-'0'), 2)
+				}
+			case byte:
+				bs.Write(part, 8)
+			}
+		}
+		return bs.Bytes()
+	}
+
+	// a tree leaning left, one level deeper than MaxCodeLength allows
+	deep := []any{}
+	for range MaxCodeLength + 1 {
+		deep = append(deep, "11")
+	}
+	deep = append(deep, "01", byte('a'))
+	for i := range MaxCodeLength + 1 {
+		deep = append(deep, "10", "01", byte('b'+i))
+	}
+
+	testCases := []struct {
+		name     string
+		input    []byte
+		contains string
+	}{
+		{name: "duplicate symbols", input: writeTree("1101", byte('a'), "1001", byte('a')), contains: "more than one leaf"},
+		{name: "wrong second child", input: writeTree("1101", byte('a'), "1101", byte('b')), contains: "second child"},
+		{name: "unknown control bits", input: writeTree("00"), contains: "control bits"},
+		{name: "truncated", input: writeTree("11111111"), contains: "attempting to read"},
+		{name: "too deep", input: writeTree(deep...), contains: "deeper than"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewNodeFromBytes(NewBitStringReader(tc.input))
+			assert.ErrorContains(t, err, tc.contains)
+		})
+	}
+}
+
+func TestValidateTree(t *testing.T) {
+	leaf := func(c byte) *Node { return &Node{freqPair: &freqPair{char: c}} }
+
+	assert.NoError(t, validateTree(&Node{left: leaf('a'), right: leaf('b')}))
+	assert.ErrorContains(t, validateTree(nil), "empty")
+	assert.ErrorContains(t, validateTree(&Node{left: leaf('a')}), "missing a child")
+	assert.ErrorContains(t, validateTree(&Node{left: leaf('a'), right: &Node{left: leaf('b')}}), "at 1 is missing")
+	assert.ErrorContains(t, validateTree(&Node{freqPair: &freqPair{char: 'a'}, left: leaf('b')}), "has children")
+	assert.ErrorContains(t, validateTree(&Node{left: leaf('a'), right: leaf('a')}), "more than one leaf")
+
+	eob := func() *Node { return &Node{freqPair: &freqPair{endOfBlock: true}} }
+	assert.NoError(t, validateTree(&Node{left: leaf(0), right: eob()}))
+	assert.ErrorContains(t, validateTree(&Node{left: eob(), right: eob()}), "more than one end of block")
+}
@@ -1,38 +1,116 @@
 package huffman
 
 import (
+	"bytes"
+	"cmp"
 	"fmt"
-	"sort"
+	"io"
+	"slices"
+
+	"github.com/mstergianis/huffman/pkg/bitio"
 )
 
+// MaxContentLength is the largest input Encode accepts, the header stores the
+// content length in 30 bits. Larger inputs can be written as a block stream.
+const MaxContentLength = 1<<30 - 1
+
 func Encode(input []byte) ([]byte, error) {
-	for i, r := range input {
-		if r > 127 || r < 0 {
-			return nil, fmt.Errorf("error: encountered a non-ascii character %c at position %d", r, i)
-		}
+	return EncodeProgress(input, nil)
+}
+
+// EncodeProgress is Encode, calling progress, if it is not nil, as input is
+// encoded.
+func EncodeProgress(input []byte, progress ProgressFunc) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := EncodeTo(buf, input, progress); err != nil {
+		return nil, err
 	}
-	ordered := computeFreqTable(input)
+	return buf.Bytes(), nil
+}
 
-	tree := NewNode(ordered)
+// EncodeTo encodes input straight to w, producing what Encode would return.
+// It calls progress, if it is not nil, as input is encoded.
+func EncodeTo(w io.Writer, input []byte, progress ProgressFunc) error {
+	if len(input) > MaxContentLength {
+		return fmt.Errorf("error: input of %d bytes is larger than the maximum content length %d", len(input), MaxContentLength)
+	}
+	return encodeTo(w, input, progress, false)
+}
 
-	bs := &BitStringWriter{}
-	bs.WriteContentLength(uint32(len(input)))
-	tree.WriteBytes(bs)
+// EncodeEndOfBlock encodes input like Encode, except that rather than storing
+// the length of input up front it ends the content with an end of block
+// symbol, so input may be longer than MaxContentLength. Decode reads either.
+func EncodeEndOfBlock(input []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := EncodeEndOfBlockTo(buf, input, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeEndOfBlockTo encodes input straight to w, producing what
+// EncodeEndOfBlock would return. It calls progress, if it is not nil, as input
+// is encoded.
+func EncodeEndOfBlockTo(w io.Writer, input []byte, progress ProgressFunc) error {
+	return encodeTo(w, input, progress, true)
+}
 
-	for _, b := range []byte(input) {
-		bytes, bitWidth := tree.Search(b)
-		if bitWidth == -1 {
-			return nil, fmt.Errorf("error: cannot find the byte %c in the tree", b)
+func encodeTo(w io.Writer, input []byte, progress ProgressFunc, endOfBlock bool) error {
+	bw := bitio.NewWriter(w)
+	var tree *Node
+	if endOfBlock {
+		bw.WriteBits(uint64(headerVersionEndOfBlock), 2)
+		tree = NewNode(append(computeFreqTable(input), freqPair{freq: 1, endOfBlock: true}))
+		writeEndOfBlockTree(bw, tree)
+	} else {
+		bw.WriteBits(uint64(headerVersionCompactTree)<<30|uint64(len(input)), 32)
+		if len(input) == 0 {
+			return bw.Flush()
 		}
-		bs.WriteBytes(bytes, bitWidth)
+		tree = writeCompactTree(bw, NewNodeFromInput(input))
+	}
+	table, err := NewCodeTable(tree)
+	if err != nil {
+		return err
 	}
 
-	return bs.Bytes(), nil
+	for i, b := range input {
+		if progress != nil && i > 0 && i%progressInterval == 0 {
+			progress(Progress{Consumed: int64(i), Produced: bw.BitsWritten() / 8})
+		}
+		code := table.codes[b]
+		if err := bw.WriteBits(code.Bits, code.Length); err != nil {
+			return err
+		}
+	}
+	if code, ok := table.EndOfBlock(); ok {
+		bw.WriteBits(code.Bits, code.Length)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if progress != nil {
+		progress(Progress{Consumed: int64(len(input)), Produced: bw.BitsWritten() / 8})
+	}
+
+	return nil
+}
+
+// NewNodeFromInput builds the tree Encode would use for input, or returns nil
+// if input is empty.
+func NewNodeFromInput(input []byte) *Node {
+	if len(input) == 0 {
+		return nil
+	}
+	return NewNode(computeFreqTable(input))
 }
 
+// freqPair is a symbol and its frequency. The end of block symbol is a
+// freqPair of its own, whose char is unused.
 type freqPair struct {
-	char byte
-	freq int
+	char       byte
+	freq       int
+	endOfBlock bool
 }
 
 func (f freqPair) Freq() int {
@@ -40,25 +118,44 @@ func (f freqPair) Freq() int {
 }
 
 func (f freqPair) String() string {
+	if f.endOfBlock {
+		return fmt.Sprintf("(EOB, %d)", f.freq)
+	}
 	return fmt.Sprintf("(%q, %d)", string(f.char), f.freq)
 }
 
+// order orders symbols when breaking ties, the end of block symbol coming
+// after every byte.
+func (f freqPair) order() int {
+	if f.endOfBlock {
+		return 256
+	}
+	return int(f.char)
+}
+
+// countSymbols counts how many times every symbol occurs in input.
+func countSymbols(input []byte) *[256]uint64 {
+	var counts [256]uint64
+	for _, b := range input {
+		counts[b]++
+	}
+	return &counts
+}
+
+// computeFreqTable counts every symbol of input, ordered by frequency and then
+// by symbol.
 func computeFreqTable(input []byte) (ordered []freqPair) {
-	freqTable := make(map[byte]int)
+	counts := countSymbols(input)
+
 	ordered = make([]freqPair, 0, 64)
-	for _, b := range []byte(input) {
-		if _, ok := freqTable[b]; !ok {
-			freqTable[b] = 0
+	for char, freq := range counts {
+		if freq > 0 {
+			ordered = append(ordered, freqPair{char: byte(char), freq: int(freq)})
 		}
-		freqTable[b]++
-	}
-
-	for k, v := range freqTable {
-		ordered = append(ordered, freqPair{char: k, freq: v})
 	}
 
-	sort.SliceStable(ordered, func(i int, j int) bool {
-		return ordered[i].freq < ordered[j].freq
+	slices.SortStableFunc(ordered, func(a, b freqPair) int {
+		return cmp.Compare(a.freq, b.freq)
 	})
 
 	return
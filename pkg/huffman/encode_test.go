@@ -1,6 +1,7 @@
 package huffman
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -134,6 +135,86 @@ func TestComputeFreqTable(t *testing.T) {
 	}
 }
 
+func TestEncodeDecode(t *testing.T) {
+	allBytes := make([]byte, 0, 512)
+	for i := range 512 {
+		allBytes = append(allBytes, byte(i*7))
+	}
+
+	testCases := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "empty input", input: []byte{}},
+		{name: "single symbol", input: []byte("aaaa")},
+		{name: "hello world", input: []byte("hello world")},
+		{name: "every byte value", input: allBytes},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded, err := Encode(tc.input)
+			assert.NoError(t, err)
+			decoded, err := Decode(encoded)
+			assert.NoError(t, err)
+			Equal(t, tc.input, decoded)
+		})
+	}
+}
+
 func Equal[E any](t assert.TestingT, expected, actual E, msgAndArgs ...any) bool {
 	return assert.Equal(t, expected, actual, msgAndArgs...)
 }
+
+func TestEncodeEndOfBlock(t *testing.T) {
+	everyByte := make([]byte, 256)
+	for i := range everyByte {
+		everyByte[i] = byte(i)
+	}
+
+	testCases := []struct {
+		name   string
+		input  []byte
+		leaves int
+	}{
+		{name: "empty input", input: []byte{}, leaves: 1},
+		{name: "single symbol", input: []byte("aaaa"), leaves: 2},
+		{name: "hello world", input: []byte("hello world"), leaves: 9},
+		{name: "every byte value", input: everyByte, leaves: 257},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			encoded, err := EncodeEndOfBlock(tc.input)
+			assert.NoError(t, err)
+
+			contentLength, tree, err := ReadHeader(NewBitStringReader(encoded))
+			assert.NoError(t, err)
+			Equal(t, uint32(UnknownContentLength), contentLength)
+			leaves, endOfBlock := 0, 0
+			for leaf := range tree.Leaves() {
+				leaves++
+				if leaf.IsEndOfBlock() {
+					endOfBlock++
+				}
+			}
+			Equal(t, tc.leaves, leaves)
+			Equal(t, 1, endOfBlock)
+
+			decoded, err := Decode(encoded)
+			assert.NoError(t, err)
+			Equal(t, tc.input, decoded)
+
+			buf := &bytes.Buffer{}
+			assert.NoError(t, EncodeEndOfBlockTo(buf, tc.input, nil))
+			Equal(t, encoded, buf.Bytes())
+		})
+	}
+}
+
+func TestEncodeTo(t *testing.T) {
+	input := []byte("streamed straight to the writer")
+	buf := &bytes.Buffer{}
+	assert.NoError(t, EncodeTo(buf, input, nil))
+	encoded, err := Encode(input)
+	assert.NoError(t, err)
+	Equal(t, encoded, buf.Bytes())
+}
@@ -0,0 +1,82 @@
+package huffman
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CorruptInputError is returned when decoding input that was not produced by
+// this package, or that has been damaged or truncated since.
+type CorruptInputError struct {
+	// Offset is the byte offset within the input at which the problem was
+	// found, or -1 when it isn't known.
+	Offset int64
+	Err    error
+}
+
+func corruptInput(offset int64, format string, a ...any) error {
+	return &CorruptInputError{Offset: offset, Err: fmt.Errorf(format, a...)}
+}
+
+func (e *CorruptInputError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CorruptInputError) Unwrap() error {
+	return e.Err
+}
+
+// ChecksumError is returned when a block of a block stream decodes, but not to
+// the contents that were checksummed when it was encoded.
+type ChecksumError struct {
+	Block int
+	// Offset is the byte offset of the block within the block stream.
+	Offset int64
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("error: block %d failed its checksum", e.Block)
+}
+
+// ErrVerifyMismatch is returned when encoded output was decoded again to check
+// it, and did not decode to the original input.
+var ErrVerifyMismatch = errors.New("error: the encoded output does not decode to its input")
+
+// shiftOffset moves the offset of a CorruptInputError or ChecksumError found
+// in a part of the input that starts at base, so that it is relative to the
+// whole input. err is left as it is, since readers return the same error again
+// on every call.
+func shiftOffset(err error, base int64) error {
+	var shifted []error
+	var corruptErr *CorruptInputError
+	if errors.As(err, &corruptErr) && corruptErr.Offset >= 0 {
+		moved := *corruptErr
+		moved.Offset += base
+		shifted = append(shifted, &moved)
+	}
+	var checksumErr *ChecksumError
+	if errors.As(err, &checksumErr) {
+		moved := *checksumErr
+		moved.Offset += base
+		shifted = append(shifted, &moved)
+	}
+	if len(shifted) == 0 {
+		return err
+	}
+	return &shiftedError{err: err, shifted: shifted}
+}
+
+// shiftedError keeps the message of err while errors.As finds the copies in
+// shifted, with their moved offsets, before the originals within err.
+type shiftedError struct {
+	err     error
+	shifted []error
+}
+
+func (e *shiftedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *shiftedError) Unwrap() []error {
+	return append(e.shifted[:len(e.shifted):len(e.shifted)], e.err)
+}
@@ -0,0 +1,221 @@
+package huffman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The exporters below describe leaves the same way: their symbol, their
+// frequency and their code. Trees read back with NewNodeFromBytes carry no
+// frequencies, so they are left out.
+
+// TreeToDot writes the nodes and edges of tree as the body of a graphviz
+// digraph. Edges are labelled with the bit they stand for and internal nodes
+// with their cumulative frequency.
+func TreeToDot(w io.Writer, tree *Node) {
+	if tree == nil {
+		return
+	}
+
+	type queued struct {
+		n    *Node
+		code string
+	}
+	hasFreqs := tree.freq > 0
+
+	q := []queued{{n: tree}}
+	for nodeID := 0; nodeID < len(q); nodeID++ {
+		n, code := q[nodeID].n, q[nodeID].code
+
+		if n.freqPair != nil {
+			fmt.Fprintf(w, "\t%d [shape=box, label=\"%s\"];\n", nodeID, dotLabel(leafLabel(n, code, hasFreqs)...))
+			continue
+		}
+
+		label := ""
+		if hasFreqs {
+			label = strconv.Itoa(n.freq)
+		}
+		fmt.Fprintf(w, "\t%d [shape=circle, label=\"%s\"];\n", nodeID, label)
+
+		for bit, child := range []*Node{n.left, n.right} {
+			if child == nil {
+				continue
+			}
+			fmt.Fprintf(w, "\t%d -> %d [label=\"%d\"];\n", nodeID, len(q), bit)
+			q = append(q, queued{n: child, code: code + strconv.Itoa(bit)})
+		}
+	}
+}
+
+// dotLabel escapes lines for use in a double quoted graphviz label and joins
+// them with graphviz's newline escape.
+func dotLabel(lines ...string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for i, line := range lines {
+		lines[i] = escaper.Replace(line)
+	}
+	return strings.Join(lines, `\n`)
+}
+
+type jsonNode struct {
+	Freq       int       `json:"freq,omitempty"`
+	Symbol     *byte     `json:"symbol,omitempty"`
+	EndOfBlock bool      `json:"endOfBlock,omitempty"`
+	Code       *string   `json:"code,omitempty"`
+	Left       *jsonNode `json:"left,omitempty"`
+	Right      *jsonNode `json:"right,omitempty"`
+}
+
+// TreeToJSON writes tree as a JSON object. Internal nodes have a left and a
+// right child, leaves have a symbol and a code. The end of block leaf has
+// endOfBlock set instead of a symbol.
+func TreeToJSON(w io.Writer, tree *Node) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(toJSONNode(tree, ""))
+}
+
+func toJSONNode(n *Node, code string) *jsonNode {
+	if n == nil {
+		return nil
+	}
+	if n.IsEndOfBlock() {
+		return &jsonNode{Freq: n.freq, EndOfBlock: true, Code: &code}
+	}
+	if n.freqPair != nil {
+		return &jsonNode{Freq: n.freq, Symbol: &n.freqPair.char, Code: &code}
+	}
+	return &jsonNode{
+		Freq:  n.freq,
+		Left:  toJSONNode(n.left, code+"0"),
+		Right: toJSONNode(n.right, code+"1"),
+	}
+}
+
+// TreeToMermaid writes tree as a Mermaid flowchart.
+func TreeToMermaid(w io.Writer, tree *Node) {
+	fmt.Fprintln(w, "flowchart TD")
+	if tree == nil {
+		return
+	}
+	hasFreqs := tree.freq > 0
+
+	var nextID int
+	var walk func(n *Node, code string) int
+	walk = func(n *Node, code string) int {
+		nodeID := nextID
+		nextID++
+
+		if n.freqPair != nil {
+			fmt.Fprintf(w, "    n%d[\"%s\"]\n", nodeID, mermaidLabel(leafLabel(n, code, hasFreqs)...))
+			return nodeID
+		}
+
+		label := "*"
+		if hasFreqs {
+			label = strconv.Itoa(n.freq)
+		}
+		fmt.Fprintf(w, "    n%d((\"%s\"))\n", nodeID, label)
+		for bit, child := range []*Node{n.left, n.right} {
+			if child == nil {
+				continue
+			}
+			childID := walk(child, code+strconv.Itoa(bit))
+			fmt.Fprintf(w, "    n%d -->|%d| n%d\n", nodeID, bit, childID)
+		}
+		return nodeID
+	}
+	walk(tree, "")
+}
+
+// mermaidLabel escapes lines for use in a double quoted Mermaid label and
+// joins them with line breaks.
+func mermaidLabel(lines ...string) string {
+	escaper := strings.NewReplacer(`#`, `#35;`, `"`, `#quot;`, `<`, `#lt;`, `>`, `#gt;`)
+	for i, line := range lines {
+		lines[i] = escaper.Replace(line)
+	}
+	return strings.Join(lines, "<br/>")
+}
+
+// TreeToASCII writes tree as an indented outline, drawn with ASCII characters.
+func TreeToASCII(w io.Writer, tree *Node) {
+	treeToText(w, tree, asciiBranches)
+}
+
+// TreeToUnicode writes tree as an indented outline, drawn with Unicode box
+// drawing characters.
+func TreeToUnicode(w io.Writer, tree *Node) {
+	treeToText(w, tree, unicodeBranches)
+}
+
+type textBranches struct {
+	middle, last, line, space string
+}
+
+var (
+	asciiBranches   = textBranches{middle: "+-- ", last: "`-- ", line: "|   ", space: "    "}
+	unicodeBranches = textBranches{middle: "├── ", last: "└── ", line: "│   ", space: "    "}
+)
+
+func treeToText(w io.Writer, tree *Node, branches textBranches) {
+	if tree == nil {
+		return
+	}
+	hasFreqs := tree.freq > 0
+
+	var walk func(n *Node, code, indent, branch string)
+	walk = func(n *Node, code, indent, branch string) {
+		edge := ""
+		if code != "" {
+			edge = code[len(code)-1:] + ": "
+		}
+
+		if n.freqPair != nil {
+			fmt.Fprintf(w, "%s%s%s%s\n", indent, branch, edge, strings.Join(leafLabel(n, code, hasFreqs), ", "))
+			return
+		}
+
+		label := "*"
+		if hasFreqs {
+			label = strconv.Itoa(n.freq)
+		}
+		fmt.Fprintf(w, "%s%s%s(%s)\n", indent, branch, edge, label)
+
+		// children are indented under the branch that leads to this node
+		switch branch {
+		case branches.middle:
+			indent += branches.line
+		case branches.last:
+			indent += branches.space
+		}
+		if n.left != nil {
+			childBranch := branches.middle
+			if n.right == nil {
+				childBranch = branches.last
+			}
+			walk(n.left, code+"0", indent, childBranch)
+		}
+		if n.right != nil {
+			walk(n.right, code+"1", indent, branches.last)
+		}
+	}
+	walk(tree, "", "", "")
+}
+
+// leafLabel describes a leaf by its symbol, frequency and code. The end of
+// block leaf is labelled EOB.
+func leafLabel(n *Node, code string, hasFreqs bool) []string {
+	label := []string{strconv.Quote(string([]byte{n.freqPair.char}))}
+	if n.IsEndOfBlock() {
+		label[0] = "EOB"
+	}
+	if hasFreqs {
+		label = append(label, fmt.Sprintf("freq: %d", n.freq))
+	}
+	return append(label, fmt.Sprintf("code: %s", code))
+}
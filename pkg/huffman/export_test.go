@@ -0,0 +1,135 @@
+package huffman
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// exportTree is a small tree with frequencies and a symbol that needs escaping
+// in every format.
+func exportTree() *Node {
+	return &Node{
+		freq: 4,
+		left: &Node{freq: 2, freqPair: &freqPair{char: 'o', freq: 2}},
+		right: &Node{
+			freq:  2,
+			left:  &Node{freq: 1, freqPair: &freqPair{char: '"', freq: 1}},
+			right: &Node{freq: 1, freqPair: &freqPair{char: 'r', freq: 1}},
+		},
+	}
+}
+
+func TestTreeToDot(t *testing.T) {
+	t.Run("with frequencies", func(t *testing.T) {
+		s := &strings.Builder{}
+		TreeToDot(s, exportTree())
+		Equal(t, strings.Join([]string{
+			`	0 [shape=circle, label="4"];`,
+			`	0 -> 1 [label="0"];`,
+			`	0 -> 2 [label="1"];`,
+			`	1 [shape=box, label="\"o\"\nfreq: 2\ncode: 0"];`,
+			`	2 [shape=circle, label="2"];`,
+			`	2 -> 3 [label="0"];`,
+			`	2 -> 4 [label="1"];`,
+			`	3 [shape=box, label="\"\\\"\"\nfreq: 1\ncode: 10"];`,
+			`	4 [shape=box, label="\"r\"\nfreq: 1\ncode: 11"];`,
+			``,
+		}, "\n"), s.String())
+	})
+
+	t.Run("without frequencies", func(t *testing.T) {
+		tree := &Node{
+			left:  &Node{freqPair: &freqPair{char: 'l'}},
+			right: &Node{freqPair: &freqPair{char: 'r'}},
+		}
+		s := &strings.Builder{}
+		TreeToDot(s, tree)
+		Equal(t, strings.Join([]string{
+			`	0 [shape=circle, label=""];`,
+			`	0 -> 1 [label="0"];`,
+			`	0 -> 2 [label="1"];`,
+			`	1 [shape=box, label="\"l\"\ncode: 0"];`,
+			`	2 [shape=box, label="\"r\"\ncode: 1"];`,
+			``,
+		}, "\n"), s.String())
+	})
+}
+
+func TestTreeToJSON(t *testing.T) {
+	s := &strings.Builder{}
+	assert.NoError(t, TreeToJSON(s, &Node{
+		left:  &Node{freqPair: &freqPair{char: 'l'}},
+		right: &Node{freqPair: &freqPair{char: 'r'}},
+	}))
+	assert.JSONEq(t, `{
+		"left": {"symbol": 108, "code": "0"},
+		"right": {"symbol": 114, "code": "1"}
+	}`, s.String())
+
+	s.Reset()
+	assert.NoError(t, TreeToJSON(s, exportTree()))
+	assert.JSONEq(t, `{
+		"freq": 4,
+		"left": {"freq": 2, "symbol": 111, "code": "0"},
+		"right": {
+			"freq": 2,
+			"left": {"freq": 1, "symbol": 34, "code": "10"},
+			"right": {"freq": 1, "symbol": 114, "code": "11"}
+		}
+	}`, s.String())
+}
+
+func TestTreeToMermaid(t *testing.T) {
+	s := &strings.Builder{}
+	TreeToMermaid(s, exportTree())
+	Equal(t, strings.Join([]string{
+		`flowchart TD`,
+		`    n0(("4"))`,
+		`    n1["#quot;o#quot;<br/>freq: 2<br/>code: 0"]`,
+		`    n0 -->|0| n1`,
+		`    n2(("2"))`,
+		`    n3["#quot;\#quot;#quot;<br/>freq: 1<br/>code: 10"]`,
+		`    n2 -->|0| n3`,
+		`    n4["#quot;r#quot;<br/>freq: 1<br/>code: 11"]`,
+		`    n2 -->|1| n4`,
+		`    n0 -->|1| n2`,
+		``,
+	}, "\n"), s.String())
+}
+
+func TestTreeToText(t *testing.T) {
+	t.Run("ascii", func(t *testing.T) {
+		s := &strings.Builder{}
+		TreeToASCII(s, exportTree())
+		Equal(t, strings.Join([]string{
+			`(4)`,
+			`+-- 0: "o", freq: 2, code: 0`,
+			"`-- 1: (2)",
+			`    +-- 0: "\"", freq: 1, code: 10`,
+			"    `-- 1: \"r\", freq: 1, code: 11",
+			``,
+		}, "\n"), s.String())
+	})
+
+	t.Run("unicode", func(t *testing.T) {
+		tree := &Node{
+			left: &Node{
+				left:  &Node{freqPair: &freqPair{char: 'a'}},
+				right: &Node{freqPair: &freqPair{char: 'b'}},
+			},
+			right: &Node{freqPair: &freqPair{char: 'c'}},
+		}
+		s := &strings.Builder{}
+		TreeToUnicode(s, tree)
+		Equal(t, strings.Join([]string{
+			`(*)`,
+			`├── 0: (*)`,
+			`│   ├── 0: "a", code: 00`,
+			`│   └── 1: "b", code: 01`,
+			`└── 1: "c", code: 1`,
+			``,
+		}, "\n"), s.String())
+	})
+}
@@ -0,0 +1,57 @@
+package huffman
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// TestGolden pins the exact bytes that Encode, EncodeEndOfBlock and BlockWriter
+// produce for every testdata/golden/*.in file, so that the same input always
+// encodes the same way. Run it with -update after a deliberate change of format.
+func TestGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "golden", "*.in"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, inputs)
+
+	for _, path := range inputs {
+		name := strings.TrimSuffix(path, ".in")
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			input, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			encoded, err := Encode(input)
+			assert.NoError(t, err)
+			checkGolden(t, name+".huff", encoded)
+
+			encoded, err = EncodeEndOfBlock(input)
+			assert.NoError(t, err)
+			checkGolden(t, name+".eob.huff", encoded)
+
+			buf := &bytes.Buffer{}
+			bw := NewBlockWriter(buf, 1024)
+			_, err = bw.Write(input)
+			assert.NoError(t, err)
+			assert.NoError(t, bw.Close())
+			checkGolden(t, name+".hfb", buf.Bytes())
+		})
+	}
+}
+
+func checkGolden(t *testing.T, path string, actual []byte) {
+	t.Helper()
+	if *update {
+		assert.NoError(t, os.WriteFile(path, actual, 0644))
+		return
+	}
+	expected, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	Equal(t, expected, actual, "%s no longer matches, run the tests with -update if the change was deliberate", path)
+}
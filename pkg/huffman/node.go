@@ -1,10 +1,11 @@
 package huffman
 
 import (
+	"cmp"
+	"container/heap"
 	"fmt"
-	"io"
+	"iter"
 	"slices"
-	"sort"
 	"strings"
 )
 
@@ -19,58 +20,79 @@ type Frequentable interface {
 	Freq() int
 }
 
+// NewNode builds the Huffman tree of the given symbols. The two least frequent
+// nodes are joined first, ties going to the node holding the lowest symbol, so
+// the same symbols always build the same tree. The end of block symbol counts
+// as higher than every byte.
+//
+// Every node of the tree is allocated up front, in one slice, and the nodes
+// waiting to be joined are kept in a heap.
 func NewNode(ordered []freqPair) *Node {
-	nodes := make([]Frequentable, len(ordered))
+	if len(ordered) == 0 {
+		return nil
+	}
 
-	for i, o := range ordered {
-		nodes[i] = o
+	pairs := slices.Clone(ordered)
+	nodes := make([]Node, 2*len(pairs)-1)
+	pending := make(nodeHeap, len(pairs))
+	for i := range pairs {
+		nodes[i] = Node{freq: pairs[i].freq, freqPair: &pairs[i]}
+		pending[i] = heapEntry{node: &nodes[i], lowest: pairs[i].order()}
+	}
+	heap.Init(&pending)
+
+	next := len(pairs)
+	for len(pending) > 1 {
+		left := pending[0]
+		last := len(pending) - 1
+		pending[0] = pending[last]
+		pending = pending[:last]
+		heap.Fix(&pending, 0)
+		right := pending[0]
+
+		// the joined node takes right's place, saving a pop and a push
+		n := &nodes[next]
+		next++
+		*n = Node{freq: left.node.freq + right.node.freq, left: left.node, right: right.node}
+		pending[0] = heapEntry{node: n, lowest: min(left.lowest, right.lowest)}
+		heap.Fix(&pending, 0)
 	}
 
-	for len(nodes) > 1 {
-		var newNode = Node{}
+	return pending[0].node
+}
 
-		switch left := nodes[0].(type) {
-		case freqPair:
-			{
-				newNode.left = &Node{
-					freq:     left.freq,
-					freqPair: &left,
-				}
-			}
-		case *Node:
-			{
-				newNode.left = left
-			}
-		default:
-			panic(fmt.Sprintf("unimplemented type %T: %v", left, left))
-		}
+// heapEntry is a node waiting to be joined by NewNode, along with the lowest
+// symbol below it, which breaks ties between nodes of equal frequency.
+type heapEntry struct {
+	node   *Node
+	lowest int
+}
 
-		switch right := nodes[1].(type) {
-		case freqPair:
-			{
-				newNode.right = &Node{
-					freq:     right.freq,
-					freqPair: &right,
-				}
-			}
-		case *Node:
-			{
-				newNode.right = right
-			}
-		default:
-			panic(fmt.Sprintf("unimplemented type %T: %v", right, right))
-		}
+// nodeHeap orders heapEntries by frequency, then by lowest symbol. NewNode
+// only uses heap.Init and heap.Fix, so Push and Pop are never called.
+type nodeHeap []heapEntry
 
-		newNode.freq = newNode.left.Freq() + newNode.right.Freq()
-		nodes = nodes[1:]
-		nodes[0] = &newNode
-		sort.SliceStable(nodes, func(i, j int) bool {
-			return nodes[i].Freq() < nodes[j].Freq()
-		})
-	}
+func (h nodeHeap) Len() int {
+	return len(h)
+}
+
+func (h nodeHeap) Less(i, j int) bool {
+	return cmp.Or(cmp.Compare(h[i].node.freq, h[j].node.freq), cmp.Compare(h[i].lowest, h[j].lowest)) < 0
+}
+
+func (h nodeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *nodeHeap) Push(x any) {
+	*h = append(*h, x.(heapEntry))
+}
 
-	head := nodes[0].(*Node)
-	return head
+func (h *nodeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
 }
 
 func (n *Node) Search(b byte) ([]byte, int) {
@@ -78,7 +100,7 @@ func (n *Node) Search(b byte) ([]byte, int) {
 		return nil, -1
 	}
 	if n.freqPair != nil {
-		if n.freqPair.char == b {
+		if n.freqPair.char == b && !n.freqPair.endOfBlock {
 			return []byte{0}, 0
 		}
 		return nil, -1
@@ -112,6 +134,16 @@ func (n *Node) Search(b byte) ([]byte, int) {
 	return nil, -1
 }
 
+// Codes returns the code of every symbol in the tree, written as a string of
+// '0's and '1's.
+func (n *Node) Codes() map[byte]string {
+	codes := make(map[byte]string)
+	for symbol, code := range n.AllCodes() {
+		codes[symbol] = code
+	}
+	return codes
+}
+
 func (n *Node) String() string {
 	s := &strings.Builder{}
 	fmt.Fprintf(s, "(%d =>", n.freq)
@@ -132,20 +164,98 @@ func (n *Node) String() string {
 	return s.String()
 }
 
-func (n *Node) printTreeString() string {
-	s := &strings.Builder{}
-	fmt.Fprintf(s, "(%d =>", n.freq)
+// Freq returns the number of times the symbols below n occur in the input the
+// tree was built from. Trees read back from encoded data carry no
+// frequencies, their nodes all return 0.
+func (n *Node) Freq() int {
+	return n.freq
+}
 
-	if n.freqPair != nil {
-		fmt.Fprintf(s, " f%s)", n.freqPair)
-		return s.String()
+// IsLeaf reports whether n is a leaf, which holds a symbol and has no
+// children.
+func (n *Node) IsLeaf() bool {
+	return n != nil && n.freqPair != nil
+}
+
+// IsEndOfBlock reports whether n is the leaf of the end of block symbol, which
+// ends content encoded by EncodeEndOfBlock.
+func (n *Node) IsEndOfBlock() bool {
+	return n.IsLeaf() && n.freqPair.endOfBlock
+}
+
+// Symbol returns the symbol held by a leaf, or 0 for any other node, including
+// the end of block leaf.
+func (n *Node) Symbol() byte {
+	if !n.IsLeaf() || n.freqPair.endOfBlock {
+		return 0
+	}
+	return n.freqPair.char
+}
+
+// Left returns the child that the bit 0 leads to, or nil for a leaf.
+func (n *Node) Left() *Node {
+	return n.left
+}
+
+// Right returns the child that the bit 1 leads to, or nil for a leaf.
+func (n *Node) Right() *Node {
+	return n.right
+}
+
+// Walk calls fn for n and every node below it, depth first and left before
+// right. depth is the number of edges between n and the node, and path the
+// LEFT and RIGHT steps taken to reach it. path is reused between calls and must
+// not be retained. When fn returns false the children of the node are
+// skipped.
+func (n *Node) Walk(fn func(node *Node, depth int, path []byte) bool) {
+	n.walk(fn, nil)
+}
+
+func (n *Node) walk(fn func(node *Node, depth int, path []byte) bool, path []byte) {
+	if n == nil || !fn(n, len(path), path) {
+		return
 	}
+	n.left.walk(fn, append(path, LEFT))
+	n.right.walk(fn, append(path, RIGHT))
+}
 
-	return s.String()
+// Leaves iterates over the leaves below n, from left to right.
+func (n *Node) Leaves() iter.Seq[*Node] {
+	return func(yield func(*Node) bool) {
+		stopped := false
+		n.Walk(func(node *Node, _ int, _ []byte) bool {
+			if stopped {
+				return false
+			}
+			if node.IsLeaf() {
+				stopped = !yield(node)
+			}
+			return !stopped
+		})
+	}
 }
 
-func (n *Node) Freq() int {
-	return n.freq
+// AllCodes iterates over the symbols below n and their codes, written as
+// strings of '0's and '1's, from left to right. The end of block leaf, having
+// no symbol, is left out.
+func (n *Node) AllCodes() iter.Seq2[byte, string] {
+	return func(yield func(byte, string) bool) {
+		stopped := false
+		code := make([]byte, 0, 64)
+		n.Walk(func(node *Node, _ int, path []byte) bool {
+			if stopped {
+				return false
+			}
+			if node.IsLeaf() && !node.IsEndOfBlock() {
+				code = code[:0]
+				for _, step := range path {
+					code = append(code, '0'+step)
+				}
+				stopped = !yield(node.Symbol(), string(code))
+			}
+			return !stopped
+		})
+	}
 }
 
 // WriteBytes encodes the tree as an array of bytes
@@ -192,53 +302,3 @@ func (n *Node) WriteBytes(bs *BitStringWriter) {
 
 	return
 }
-
-// printTree is a debugging tool
-func printTree(tree *Node) {
-	printTreeWithDepth(0, tree)
-}
-
-// printTreeWithDepth is a debugging tool
-func printTreeWithDepth(depth int, tree *Node) {
-	if tree == nil {
-		return
-	}
-	// print the depth, then the node
-	fmt.Printf("%s%s\n", strings.Repeat(" ", depth*2), tree.printTreeString())
-	printTreeWithDepth(depth+1, tree.left)
-	printTreeWithDepth(depth+1, tree.right)
-}
-
-func TreeToDot(w io.Writer, tree *Node) {
-	tmp := []*Node{tree}
-	q := []*Node{}
-	for len(tmp) > 0 {
-		n := tmp[0]
-		q = append(q, n)
-		if n.left != nil {
-			tmp = append(tmp, n.left)
-		}
-		if n.right != nil {
-			tmp = append(tmp, n.right)
-		}
-		tmp = tmp[1:]
-	}
-
-	for nodeID, n := range q {
-		fmt.Fprintf(w, "\t%d", nodeID)
-		if n.freqPair != nil {
-			fmt.Fprintf(w, " [label=\"char: %q\"]", n.freqPair.char)
-		}
-		fmt.Fprintln(w, ";")
-		if n.left != nil {
-			childID := slices.Index(q, n.left)
-			fmt.Fprintf(w, "\t%d -> %d;\n", nodeID, childID)
-			q = append(q, n.left)
-		}
-		if n.right != nil {
-			childID := slices.Index(q, n.right)
-			fmt.Fprintf(w, "\t%d -> %d;\n", nodeID, childID)
-		}
-		nodeID++
-	}
-}
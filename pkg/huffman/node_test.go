@@ -1,6 +1,12 @@
 package huffman
 
-import "testing"
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestSearch(t *testing.T) {
 	type searchReturn struct {
@@ -96,6 +102,20 @@ func TestSearch(t *testing.T) {
 	}
 }
 
+func TestCodes(t *testing.T) {
+	tree := &Node{
+		left: &Node{freqPair: &freqPair{char: 'o'}},
+		right: &Node{
+			left:  &Node{freqPair: &freqPair{char: 'z'}},
+			right: &Node{freqPair: &freqPair{char: 'r'}},
+		},
+	}
+	Equal(t, map[byte]string{'o': "0", 'z': "10", 'r': "11"}, tree.Codes())
+
+	leaf := &Node{freqPair: &freqPair{char: 'a'}}
+	Equal(t, map[byte]string{'a': ""}, leaf.Codes())
+}
+
 func makeHighlyRightNestedNode(depth int, char byte) *Node {
 	var head *Node = &Node{}
 	var n = head
@@ -106,3 +126,101 @@ func makeHighlyRightNestedNode(depth int, char byte) *Node {
 	n.right = &Node{freqPair: &freqPair{char: char}}
 	return head
 }
+
+func TestAccessors(t *testing.T) {
+	tree := NewNode([]freqPair{{char: 'a', freq: 1}, {char: 'b', freq: 2}})
+	Equal(t, false, tree.IsLeaf())
+	Equal(t, 3, tree.Freq())
+	Equal(t, byte(0), tree.Symbol())
+
+	Equal(t, true, tree.Left().IsLeaf())
+	Equal(t, byte('a'), tree.Left().Symbol())
+	Equal(t, 1, tree.Left().Freq())
+	Equal(t, byte('b'), tree.Right().Symbol())
+	Equal(t, (*Node)(nil), tree.Left().Left())
+
+	var nilNode *Node
+	Equal(t, false, nilNode.IsLeaf())
+}
+
+func TestWalk(t *testing.T) {
+	tree := &Node{
+		left: &Node{freqPair: &freqPair{char: 'o'}},
+		right: &Node{
+			left:  &Node{freqPair: &freqPair{char: 'z'}},
+			right: &Node{freqPair: &freqPair{char: 'r'}},
+		},
+	}
+
+	type visit struct {
+		depth int
+		path  string
+	}
+	var visits []visit
+	tree.Walk(func(n *Node, depth int, path []byte) bool {
+		code := ""
+		for _, step := range path {
+			code += string('0' + step)
+		}
+		visits = append(visits, visit{depth: depth, path: code})
+		return true
+	})
+	Equal(t, []visit{{0, ""}, {1, "0"}, {1, "1"}, {2, "10"}, {2, "11"}}, visits)
+
+	visited := 0
+	tree.Walk(func(n *Node, depth int, path []byte) bool {
+		visited++
+		return depth == 0
+	})
+	Equal(t, 3, visited)
+
+	var symbols []byte
+	for leaf := range tree.Leaves() {
+		symbols = append(symbols, leaf.Symbol())
+	}
+	Equal(t, []byte("ozr"), symbols)
+
+	var codes []string
+	for symbol, code := range tree.AllCodes() {
+		codes = append(codes, string(symbol)+"="+code)
+		if symbol == 'z' {
+			break
+		}
+	}
+	Equal(t, []string{"o=0", "z=10"}, codes)
+}
+
+// benchmarkInput returns about a MiB of text for benchmarks.
+func benchmarkInput(b *testing.B) []byte {
+	text, err := os.ReadFile(filepath.Join("testdata", "golden", "wild-wild-west.in"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return bytes.Repeat(text, 1<<20/len(text))
+}
+
+func BenchmarkNewNodeFromInput(b *testing.B) {
+	block := benchmarkInput(b)[:DefaultBlockSize]
+	b.SetBytes(int64(len(block)))
+	b.ReportAllocs()
+	for b.Loop() {
+		NewNodeFromInput(block)
+	}
+}
+
+// BenchmarkBlockWriter encodes with small blocks, building a tree for every
+// 4 KiB of input.
+func BenchmarkBlockWriter(b *testing.B) {
+	input := benchmarkInput(b)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for b.Loop() {
+		bw := NewBlockWriter(io.Discard, 4096)
+		if _, err := bw.Write(input); err != nil {
+			b.Fatal(err)
+		}
+		if err := bw.Close(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package huffman
+
+// Progress tells how far an encode or decode has come.
+type Progress struct {
+	// Consumed is the number of bytes of input read so far.
+	Consumed int64
+	// Produced is the number of bytes of output written so far.
+	Produced int64
+}
+
+// ProgressFunc is called as an encode or decode makes progress, and once more
+// when it is done.
+type ProgressFunc func(Progress)
+
+// progressInterval is how many bytes EncodeProgress and DecodeProgress handle
+// between calls of their ProgressFunc.
+const progressInterval = 64 * 1024
@@ -0,0 +1,68 @@
+package huffman
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	input := []byte(strings.Repeat("progress is reported as we go ", 10000))
+
+	// record checks that every report moves forward and returns the last one
+	record := func(t *testing.T) (ProgressFunc, func() (Progress, int)) {
+		var (
+			last  Progress
+			calls int
+		)
+		return func(p Progress) {
+				assert.GreaterOrEqual(t, p.Consumed, last.Consumed)
+				assert.GreaterOrEqual(t, p.Produced, last.Produced)
+				last = p
+				calls++
+			}, func() (Progress, int) {
+				return last, calls
+			}
+	}
+
+	t.Run("encode and decode", func(t *testing.T) {
+		progress, result := record(t)
+		encoded, err := EncodeProgress(input, progress)
+		assert.NoError(t, err)
+		last, calls := result()
+		Equal(t, Progress{Consumed: int64(len(input)), Produced: int64(len(encoded))}, last)
+		Equal(t, len(input)/progressInterval+1, calls)
+
+		progress, result = record(t)
+		decoded, err := DecodeProgress(encoded, progress)
+		assert.NoError(t, err)
+		Equal(t, input, decoded)
+		last, _ = result()
+		Equal(t, Progress{Consumed: int64(len(encoded)), Produced: int64(len(input))}, last)
+	})
+
+	t.Run("block stream", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		progress, result := record(t)
+		bw := NewBlockWriter(buf, 1000)
+		bw.OnProgress(progress)
+		_, err := bw.Write(input)
+		assert.NoError(t, err)
+		assert.NoError(t, bw.Close())
+		last, calls := result()
+		Equal(t, Progress{Consumed: int64(len(input)), Produced: int64(buf.Len())}, last)
+		Equal(t, len(input)/1000+1, calls)
+
+		progress, result = record(t)
+		br := NewBlockReader(bytes.NewReader(buf.Bytes()))
+		br.OnProgress(progress)
+		decoded, err := io.ReadAll(br)
+		assert.NoError(t, err)
+		Equal(t, input, decoded)
+		last, _ = result()
+		Equal(t, Progress{Consumed: int64(buf.Len()), Produced: int64(len(input))}, last)
+	})
+}
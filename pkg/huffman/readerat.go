@@ -0,0 +1,199 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// blockCacheSize is the number of decoded blocks a ReaderAt keeps around.
+const blockCacheSize = 8
+
+// ReaderAt gives random access to the decoded contents of a block stream. Only
+// the blocks overlapping a requested range are read and decoded, and the most
+// recently decoded blocks are cached.
+//
+// ReadAt may be called concurrently, Read and Seek share a single offset and
+// may not.
+type ReaderAt struct {
+	r      io.ReaderAt
+	index  []blockIndexEntry
+	starts []int64 // decoded offset of every block
+	size   int64   // decoded size of the whole stream
+	offset int64   // used by Read and Seek
+
+	mu    sync.Mutex
+	cache []cachedBlock // most recently used first
+}
+
+type cachedBlock struct {
+	block    int
+	contents []byte
+}
+
+// OpenReaderAt reads the index of the block stream held in r, which is size
+// bytes long.
+func OpenReaderAt(r io.ReaderAt, size int64) (*ReaderAt, error) {
+	if size < blockHeaderLen+4+blockTrailerLen {
+		return nil, corruptInput(0, "error: a block stream of %d bytes is too short", size)
+	}
+
+	header := make([]byte, blockHeaderLen)
+	if err := readFullAt(r, header, 0); err != nil {
+		return nil, err
+	}
+	if !IsBlockStream(header) {
+		return nil, corruptInput(0, "error: input is not a block stream, header was %x", header)
+	}
+
+	trailer := make([]byte, blockTrailerLen)
+	if err := readFullAt(r, trailer, size-blockTrailerLen); err != nil {
+		return nil, err
+	}
+	if [4]byte(trailer[12:]) != indexMagic {
+		return nil, corruptInput(size-blockTrailerLen, "error: block stream trailer ended with %x, expected %x", trailer[12:], indexMagic)
+	}
+	indexOffset := int64(binary.BigEndian.Uint64(trailer[0:8]))
+	count := int64(binary.BigEndian.Uint32(trailer[8:12]))
+	if indexOffset < blockHeaderLen+4 || indexOffset+count*blockIndexEntryLen+blockTrailerLen != size {
+		return nil, corruptInput(size-blockTrailerLen, "error: block stream index at %d with %d entries does not fit a stream of %d bytes", indexOffset, count, size)
+	}
+
+	raw := make([]byte, count*blockIndexEntryLen)
+	if err := readFullAt(r, raw, indexOffset); err != nil {
+		return nil, err
+	}
+
+	ra := &ReaderAt{
+		r:      r,
+		index:  make([]blockIndexEntry, count),
+		starts: make([]int64, count),
+	}
+	for i := range ra.index {
+		entry := parseBlockIndexEntry(raw[i*blockIndexEntryLen:])
+		if entry.offset < blockHeaderLen+4 || entry.offset+int64(entry.length) > indexOffset-4 {
+			return nil, corruptInput(indexOffset+int64(i*blockIndexEntryLen), "error: block %d at %d with length %d lies outside of the stream's blocks", i, entry.offset, entry.length)
+		}
+		ra.index[i] = entry
+		ra.starts[i] = ra.size
+		ra.size += int64(entry.size)
+	}
+
+	return ra, nil
+}
+
+// Size returns the decoded size of the stream.
+func (ra *ReaderAt) Size() int64 {
+	return ra.size
+}
+
+// NumBlocks returns the number of blocks in the stream.
+func (ra *ReaderAt) NumBlocks() int {
+	return len(ra.index)
+}
+
+func (ra *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("error: ReaderAt.ReadAt called with negative offset %d", off)
+	}
+	if off >= ra.size {
+		return 0, io.EOF
+	}
+
+	// find the last block starting at or before off
+	block := sort.Search(len(ra.starts), func(i int) bool {
+		return ra.starts[i] > off
+	}) - 1
+
+	n := 0
+	for n < len(p) && block < len(ra.index) {
+		contents, err := ra.block(block)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], contents[off+int64(n)-ra.starts[block]:])
+		block++
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (ra *ReaderAt) Read(p []byte) (int, error) {
+	n, err := ra.ReadAt(p, ra.offset)
+	ra.offset += int64(n)
+	if errors.Is(err, io.EOF) && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (ra *ReaderAt) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += ra.offset
+	case io.SeekEnd:
+		offset += ra.size
+	default:
+		return 0, fmt.Errorf("error: ReaderAt.Seek called with invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("error: ReaderAt.Seek to negative position %d", offset)
+	}
+	ra.offset = offset
+	return offset, nil
+}
+
+// block returns the decoded contents of block i, decoding it if it isn't
+// cached.
+func (ra *ReaderAt) block(i int) ([]byte, error) {
+	ra.mu.Lock()
+	for j, c := range ra.cache {
+		if c.block == i {
+			copy(ra.cache[1:j+1], ra.cache[:j])
+			ra.cache[0] = c
+			ra.mu.Unlock()
+			return c.contents, nil
+		}
+	}
+	ra.mu.Unlock()
+
+	e := ra.index[i]
+	payload := make([]byte, e.length)
+	if err := readFullAt(ra.r, payload, e.offset); err != nil {
+		return nil, err
+	}
+	contents, err := decodeBlock(payload, e, i)
+	if err != nil {
+		return nil, err
+	}
+
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	if len(ra.cache) < blockCacheSize {
+		ra.cache = append(ra.cache, cachedBlock{})
+	}
+	copy(ra.cache[1:], ra.cache)
+	ra.cache[0] = cachedBlock{block: i, contents: contents}
+
+	return contents, nil
+}
+
+// readFullAt fills p from r at off. Unlike a bare ReadAt it accepts io.EOF
+// alongside a full read, and reports a short read as a truncated stream.
+func readFullAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil || errors.Is(err, io.EOF) {
+		return &CorruptInputError{Offset: off + int64(n), Err: io.ErrUnexpectedEOF}
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Verify fully decodes input, which may be an encoded file, a block stream or
+// an archive, and discards the result. It returns a CorruptInputError or a
+// ChecksumError describing the first problem found.
+//
+// Verify is stricter than Decode: encoded files must end with the padding of
+// their last byte, and that padding must be zeroes, as Encode writes it.
+// Encoded files carry no checksum though, so for them Verify can only check
+// that they decode, see HasIntegrityData.
+func Verify(input []byte) error {
+	switch {
+	case IsArchive(input):
+		ar, err := OpenArchive(bytes.NewReader(input), int64(len(input)))
+		if err != nil {
+			return err
+		}
+		for _, e := range ar.Entries {
+			if _, err := io.Copy(io.Discard, ar.Open(e)); err != nil {
+				return fmt.Errorf("error: while verifying %q: %w", e.Path, err)
+			}
+		}
+		return nil
+	case IsBlockStream(input):
+		br := NewBlockReader(bytes.NewReader(input))
+		if _, err := io.Copy(io.Discard, br); err != nil {
+			return err
+		}
+		if br.read < int64(len(input)) {
+			return corruptInput(br.read, "error: %d bytes follow the end of the block stream", int64(len(input))-br.read)
+		}
+		return nil
+	}
+
+	_, bs, err := decode(input, nil)
+	if err != nil {
+		return err
+	}
+
+	// the last byte is padded out with zeroes
+	used := bs.currentByte
+	if bs.offset > 0 {
+		if input[bs.currentByte]&onesMask(8-bs.offset) != 0 {
+			return corruptInput(int64(bs.currentByte), "error: the padding of the last byte is %0*b, expected zeroes", 8-bs.offset, input[bs.currentByte]&onesMask(8-bs.offset))
+		}
+		used++
+	}
+	if used < len(input) {
+		return corruptInput(int64(used), "error: %d bytes follow the end of the encoded content", len(input)-used)
+	}
+	return nil
+}
+
+// HasIntegrityData reports whether input carries checksums that Verify checks
+// the decoded content against, as block streams and archives do. Plain encoded
+// files carry none.
+func HasIntegrityData(input []byte) bool {
+	return IsArchive(input) || IsBlockStream(input)
+}
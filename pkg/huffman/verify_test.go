@@ -0,0 +1,77 @@
+package huffman
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	input := []byte(strings.Repeat("verify me, ", 20))
+	encoded, err := Encode(input)
+	assert.NoError(t, err)
+
+	t.Run("encoded file", func(t *testing.T) {
+		assert.NoError(t, Verify(encoded))
+		assert.False(t, HasIntegrityData(encoded))
+	})
+
+	t.Run("trailing bytes", func(t *testing.T) {
+		err := Verify(append(bytes.Clone(encoded), 0))
+		var corruptErr *CorruptInputError
+		assert.ErrorAs(t, err, &corruptErr)
+		Equal(t, int64(len(encoded)), corruptErr.Offset)
+	})
+
+	t.Run("padding", func(t *testing.T) {
+		// "ab" encodes to 2 bits of content, leaving 6 bits of padding
+		encoded, err := Encode([]byte("ab"))
+		assert.NoError(t, err)
+		encoded[len(encoded)-1] |= 1
+
+		err = Verify(encoded)
+		var corruptErr *CorruptInputError
+		assert.ErrorAs(t, err, &corruptErr)
+		Equal(t, int64(len(encoded)-1), corruptErr.Offset)
+	})
+
+	t.Run("block stream", func(t *testing.T) {
+		stream := encodeBlockStream(t, input, 64)
+		assert.NoError(t, Verify(stream))
+		assert.True(t, HasIntegrityData(stream))
+
+		ra, err := OpenReaderAt(bytes.NewReader(stream), int64(len(stream)))
+		assert.NoError(t, err)
+		corrupted := bytes.Clone(stream)
+		indexOffset := len(stream) - blockTrailerLen - blockIndexEntryLen*len(ra.index)
+		corrupted[indexOffset+blockIndexEntryLen+19] ^= 1
+
+		err = Verify(corrupted)
+		var checksumErr *ChecksumError
+		assert.ErrorAs(t, err, &checksumErr)
+		Equal(t, 1, checksumErr.Block)
+		Equal(t, ra.index[1].offset, checksumErr.Offset)
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		aw := NewArchiveWriter(buf)
+		assert.NoError(t, aw.Add(ArchiveEntry{Path: "a.txt", Mode: 0644, Size: int64(len(input))}, bytes.NewReader(input)))
+		assert.NoError(t, aw.Close())
+		assert.NoError(t, Verify(buf.Bytes()))
+		assert.True(t, HasIntegrityData(buf.Bytes()))
+	})
+}
+
+func TestBlockWriterVerifyBlocks(t *testing.T) {
+	input := []byte(strings.Repeat("verified blocks ", 30))
+	buf := &bytes.Buffer{}
+	bw := NewBlockWriter(buf, 100)
+	bw.VerifyBlocks()
+	_, err := bw.Write(input)
+	assert.NoError(t, err)
+	assert.NoError(t, bw.Close())
+	assert.NoError(t, Verify(buf.Bytes()))
+}
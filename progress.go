@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mstergianis/huffman/pkg/huffman"
+)
+
+const (
+	progressBarWidth = 30
+	progressRedraw   = 100 * time.Millisecond
+)
+
+// progressBar draws the progress of an encode or decode on stderr. A nil
+// progressBar draws nothing.
+type progressBar struct {
+	w        io.Writer
+	total    int64
+	start    time.Time
+	drawn    time.Time
+	progress huffman.Progress
+	done     bool
+}
+
+// newProgressBar returns a progress bar for input, or nil unless it is
+// enabled and stderr is a terminal. The bar is measured against the length of
+// input when input is a regular file.
+func newProgressBar(enabled bool, input *os.File) *progressBar {
+	if !enabled || !isTerminal(os.Stderr) {
+		return nil
+	}
+	b := &progressBar{w: os.Stderr, total: -1, start: time.Now()}
+	if info, err := input.Stat(); err == nil && info.Mode().IsRegular() {
+		b.total = info.Size()
+	}
+	return b
+}
+
+// callback returns the function to hand to the library, or nil for a nil
+// progressBar.
+func (b *progressBar) callback() huffman.ProgressFunc {
+	if b == nil {
+		return nil
+	}
+	return b.update
+}
+
+func (b *progressBar) update(p huffman.Progress) {
+	b.progress = p
+	if now := time.Now(); now.Sub(b.drawn) >= progressRedraw {
+		b.drawn = now
+		b.draw(now)
+	}
+}
+
+// finish draws the bar as it ended and moves past it.
+func (b *progressBar) finish() {
+	if b == nil || b.done {
+		return
+	}
+	b.done = true
+	b.draw(time.Now())
+	fmt.Fprintln(b.w)
+}
+
+// close moves past the bar as it was last drawn unless finish was called, so
+// that an error reported after a failed encode or decode starts on a line of
+// its own. It is meant to be deferred.
+func (b *progressBar) close() {
+	if b == nil || b.done {
+		return
+	}
+	b.done = true
+	if !b.drawn.IsZero() {
+		fmt.Fprintln(b.w)
+	}
+}
+
+func (b *progressBar) draw(now time.Time) {
+	elapsed := now.Sub(b.start)
+	consumed := b.progress.Consumed
+	rate := megabytesPerSecond(int(consumed), elapsed)
+
+	if b.total <= 0 {
+		fmt.Fprintf(b.w, "\r%8.1f MB  %6.1f MB/s\033[K", float64(consumed)/1e6, rate)
+		return
+	}
+
+	fraction := min(float64(consumed)/float64(b.total), 1)
+	filled := int(fraction * progressBarWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+
+	eta := "--:--"
+	if consumed > 0 {
+		left := time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+		eta = formatETA(left)
+	}
+	fmt.Fprintf(b.w, "\r[%s] %3.0f%%  %6.1f MB/s  ETA %s\033[K", bar, fraction*100, rate, eta)
+}
+
+func formatETA(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d >= time.Hour {
+		return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+	}
+	return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mstergianis/huffman/pkg/huffman"
+)
+
+func verify(args []string) error {
+	flags := newFlagSet("verify", "FILE...",
+		"Decodes every FILE, - means stdin, without writing the result anywhere, and\n"+
+			"checks it against the integrity data it carries. Encoded files, block streams\n"+
+			"and archives are all accepted. Plain encoded files carry no integrity data, so\n"+
+			"they are only checked to decode, which is reported as DECODED rather than OK.")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return usageErrorf("verify", "expected at least one file")
+	}
+
+	var (
+		failed   int
+		firstErr error
+	)
+	for _, name := range flags.Args() {
+		checked, err := verifyFile(name)
+		if err == nil {
+			if checked {
+				fmt.Printf("%s: OK\n", name)
+			} else {
+				fmt.Printf("%s: DECODED no integrity data, decode check only\n", name)
+			}
+			continue
+		}
+
+		failed++
+		if firstErr == nil {
+			firstErr = err
+		}
+		fmt.Printf("%s: FAILED %s\n", name, describeVerifyError(err))
+	}
+
+	if failed > 0 {
+		return &verifyFailedError{failed: failed, total: flags.NArg(), err: firstErr}
+	}
+	return nil
+}
+
+// verifyFile verifies the file name and reports whether it carried integrity
+// data to check its content against.
+func verifyFile(name string) (bool, error) {
+	input, err := openInput(name)
+	if err != nil {
+		return false, err
+	}
+	defer input.Close()
+
+	contents, err := io.ReadAll(input)
+	if err != nil {
+		return false, err
+	}
+	return huffman.HasIntegrityData(contents), huffman.Verify(contents)
+}
+
+// describeVerifyError says where in the file err was found, when it is known.
+func describeVerifyError(err error) string {
+	message := strings.TrimPrefix(err.Error(), "error: ")
+
+	var (
+		checksumErr *huffman.ChecksumError
+		corruptErr  *huffman.CorruptInputError
+	)
+	switch {
+	case errors.As(err, &checksumErr):
+		return fmt.Sprintf("at byte %d: %s", checksumErr.Offset, message)
+	case errors.As(err, &corruptErr) && corruptErr.Offset >= 0:
+		return fmt.Sprintf("at byte %d: %s", corruptErr.Offset, message)
+	}
+	return message
+}
+
+// verifyFailedError sums up a verify run in which some files failed. It wraps
+// the first failure, which decides the exit code.
+type verifyFailedError struct {
+	failed, total int
+	err           error
+}
+
+func (e *verifyFailedError) Error() string {
+	return fmt.Sprintf("%d of %d files failed verification", e.failed, e.total)
+}
+
+func (e *verifyFailedError) Unwrap() error {
+	return e.err
+}